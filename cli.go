@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"top-ai-news/internal/auth"
+	"top-ai-news/internal/database"
+	"top-ai-news/internal/fetcher"
+)
+
+const usage = `用法:
+  top-ai-news [flags]                  启动 Web 服务
+  top-ai-news fetch [flags]            抓取一次新闻并写入数据库
+  top-ai-news backfill [flags]         按日期范围回填历史榜单
+  top-ai-news hash-token [-new]        从标准输入读取管理员令牌并计算哈希（-new 生成新令牌）
+  top-ai-news opml export [flags]      导出 RSS 源为 OPML
+  top-ai-news opml import [flags] FILE 从 OPML 导入 RSS 源到配置文件
+
+运行 top-ai-news <command> -h 查看子命令参数
+`
+
+// runCommand executes a CLI subcommand and returns the process exit code.
+func runCommand(args []string) int {
+	switch {
+	case args[0] == "fetch":
+		return runFetch(args[1:])
+	case args[0] == "backfill":
+		return runBackfill(args[1:])
+	case args[0] == "hash-token":
+		return runHashToken(args[1:])
+	case len(args) >= 2 && args[0] == "opml" && args[1] == "export":
+		return runOPMLExport(args[2:])
+	case len(args) >= 2 && args[0] == "opml" && args[1] == "import":
+		return runOPMLImport(args[2:])
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		return 2
+	}
+}
+
+func runFetch(args []string) int {
+	fset := flag.NewFlagSet("fetch", flag.ExitOnError)
+	dbPath := fset.String("db", "data.db", "数据库文件路径")
+	sourcesPath := fset.String("sources", "", "RSS 源配置文件路径，为空则使用内置源")
+	date := fset.String("date", "", "新闻日期 (yyyy-MM-dd)，缺省为日报时区的今天")
+	force := fset.Bool("force", false, "强制重写已锁定的日报")
+	tz := timezoneFlag(fset)
+	fset.Parse(args)
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "时区无效: %v\n", err)
+		return 2
+	}
+	if *date != "" {
+		if _, err := time.Parse("2006-01-02", *date); err != nil {
+			fmt.Fprintln(os.Stderr, "日期格式无效，请使用 yyyy-MM-dd")
+			return 2
+		}
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "数据库初始化失败: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	f, err := fetcher.New(db, *sourcesPath, loc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载 RSS 源配置失败:\n%v\n", err)
+		return 1
+	}
+	if *date == "" {
+		*date = f.Today()
+	}
+	if err := f.FetchAndStore(*date, fetcher.TriggerCLI, *force); err != nil {
+		fmt.Fprintf(os.Stderr, "抓取新闻失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runBackfill(args []string) int {
+	fset := flag.NewFlagSet("backfill", flag.ExitOnError)
+	dbPath := fset.String("db", "data.db", "数据库文件路径")
+	sourcesPath := fset.String("sources", "", "RSS 源配置文件路径，为空则使用内置源")
+	from := fset.String("from", "", "起始日期 (yyyy-MM-dd，必填)")
+	to := fset.String("to", "", "结束日期 (yyyy-MM-dd，含当天，缺省同 -from)")
+	force := fset.Bool("force", false, "强制重写已锁定的日报")
+	tz := timezoneFlag(fset)
+	fset.Parse(args)
+
+	if *from == "" {
+		fmt.Fprintln(os.Stderr, "用法: top-ai-news backfill -from 2026-02-01 [-to 2026-02-07]")
+		return 2
+	}
+	if *to == "" {
+		*to = *from
+	}
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "时区无效: %v\n", err)
+		return 2
+	}
+
+	db, err := database.New(*dbPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "数据库初始化失败: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	f, err := fetcher.New(db, *sourcesPath, loc)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载 RSS 源配置失败:\n%v\n", err)
+		return 1
+	}
+	stored, err := f.Backfill(*from, *to, fetcher.TriggerCLI, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "回填失败: %v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ 回填 %s..%s 完成，共保存 %d 条新闻\n", *from, *to, stored)
+	return 0
+}
+
+// runHashToken prints the hash of a token read from stdin, or of a new one.
+// The token is never taken from the command line, where it would be kept in
+// the shell history and shown by ps.
+func runHashToken(args []string) int {
+	fset := flag.NewFlagSet("hash-token", flag.ExitOnError)
+	generate := fset.Bool("new", false, "生成新令牌，而不是从标准输入读取")
+	fset.Parse(args)
+	if fset.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "请通过标准输入传入令牌，命令行参数会留在 shell 历史和 ps 中")
+		return 2
+	}
+
+	var token string
+	if *generate {
+		var err error
+		if token, err = auth.GenerateToken(); err != nil {
+			fmt.Fprintf(os.Stderr, "生成令牌失败: %v\n", err)
+			return 1
+		}
+		fmt.Printf("token: %s\n", token)
+	} else {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(os.Stderr, "读取令牌失败: %v\n", err)
+			return 1
+		}
+		if token = strings.TrimSpace(line); token == "" {
+			fmt.Fprintln(os.Stderr, "标准输入中没有令牌（生成新令牌请用 -new）")
+			return 2
+		}
+	}
+	fmt.Printf("hash:  %s\n", auth.HashToken(token))
+	return 0
+}
+
+func runOPMLExport(args []string) int {
+	fset := flag.NewFlagSet("opml export", flag.ExitOnError)
+	sourcesPath := fset.String("sources", "", "RSS 源配置文件路径，为空则导出内置源")
+	out := fset.String("o", "", "输出文件，为空则写到标准输出")
+	fset.Parse(args)
+
+	set, err := fetcher.LoadSources(*sourcesPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "加载 RSS 源配置失败:\n%v\n", err)
+		return 1
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "创建输出文件失败: %v\n", err)
+			return 1
+		}
+		defer file.Close()
+		w = file
+	}
+	if err := fetcher.ExportOPML(w, set.Feeds); err != nil {
+		fmt.Fprintf(os.Stderr, "导出 OPML 失败: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func runOPMLImport(args []string) int {
+	fset := flag.NewFlagSet("opml import", flag.ExitOnError)
+	sourcesPath := fset.String("sources", "", "要写入的 RSS 源配置文件路径（必填）")
+	category := fset.String("category", "", "文件夹不是已配置的分类时使用的默认分类")
+	fset.Parse(args)
+
+	if *sourcesPath == "" || fset.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "用法: top-ai-news opml import -sources sources.yaml [-category global] FILE.opml")
+		return 2
+	}
+
+	in, err := os.Open(fset.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "打开 OPML 文件失败: %v\n", err)
+		return 1
+	}
+	defer in.Close()
+
+	added, err := fetcher.ImportOPML(*sourcesPath, in, *category)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "导入 OPML 失败:\n%v\n", err)
+		return 1
+	}
+	fmt.Printf("✓ 已导入 %d 个 RSS 源到 %s\n", added, *sourcesPath)
+	return 0
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Scope limits what an admin token may do.
+type Scope string
+
+const (
+	ScopeFetch      Scope = "fetch"      // trigger fetch runs, view run history
+	ScopeSources    Scope = "sources"    // view and change feed sources
+	ScopeModeration Scope = "moderation" // delete comments
+)
+
+var validScopes = map[Scope]bool{ScopeFetch: true, ScopeSources: true, ScopeModeration: true}
+
+// EnvTokens is the environment variable holding tokens in the form
+// "name:sha256hex:scope1,scope2", several separated by ";".
+const EnvTokens = "ADMIN_TOKENS"
+
+var (
+	ErrNoToken      = errors.New("missing bearer token")
+	ErrInvalidToken = errors.New("invalid token")
+	ErrForbidden    = errors.New("token lacks required scope")
+)
+
+// Token is a configured admin token. Only the SHA-256 hash of the secret is kept.
+type Token struct {
+	Name   string  `yaml:"name"`
+	Hash   string  `yaml:"hash"` // hex-encoded SHA-256 of the bearer token
+	Scopes []Scope `yaml:"scopes"`
+
+	hash []byte
+}
+
+type tokensFile struct {
+	Tokens []Token `yaml:"tokens"`
+}
+
+// Authenticator verifies bearer tokens against the configured hashes.
+type Authenticator struct {
+	tokens []Token
+}
+
+// Load builds an Authenticator from the tokens file at path (may be empty)
+// plus any tokens in the ADMIN_TOKENS environment variable.
+func Load(path string) (*Authenticator, error) {
+	var tokens []Token
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read auth file: %w", err)
+		}
+		var file tokensFile
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&file); err != nil {
+			return nil, fmt.Errorf("%s: parse auth file: %w", path, err)
+		}
+		tokens = append(tokens, file.Tokens...)
+	}
+
+	envTokens, err := ParseTokens(os.Getenv(EnvTokens))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", EnvTokens, err)
+	}
+	tokens = append(tokens, envTokens...)
+
+	var errs []error
+	seen := make(map[string]bool)
+	for i := range tokens {
+		t := &tokens[i]
+		if err := t.validate(); err != nil {
+			errs = append(errs, fmt.Errorf("token %q: %w", t.Name, err))
+			continue
+		}
+		if seen[t.Name] {
+			errs = append(errs, fmt.Errorf("token %q: duplicate name", t.Name))
+		}
+		seen[t.Name] = true
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &Authenticator{tokens: tokens}, nil
+}
+
+// ParseTokens parses the ADMIN_TOKENS format "name:sha256hex:scope1,scope2;...".
+func ParseTokens(s string) ([]Token, error) {
+	var tokens []Token
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		fields := strings.Split(part, ":")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("bad token entry %q, want name:hash:scopes", part)
+		}
+		t := Token{Name: fields[0], Hash: fields[1]}
+		for _, sc := range strings.Split(fields[2], ",") {
+			if sc = strings.TrimSpace(sc); sc != "" {
+				t.Scopes = append(t.Scopes, Scope(sc))
+			}
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+func (t *Token) validate() error {
+	if t.Name == "" {
+		return errors.New("name is required")
+	}
+	h, err := hex.DecodeString(strings.TrimPrefix(t.Hash, "sha256:"))
+	if err != nil || len(h) != sha256.Size {
+		return errors.New("hash must be a hex-encoded SHA-256 (see: top-ai-news hash-token)")
+	}
+	t.hash = h
+	if len(t.Scopes) == 0 {
+		return errors.New("at least one scope is required")
+	}
+	for _, sc := range t.Scopes {
+		if !validScopes[sc] {
+			return fmt.Errorf("unknown scope %q", sc)
+		}
+	}
+	return nil
+}
+
+// Enabled reports whether any token is configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.tokens) > 0
+}
+
+// Authorize checks the request's bearer token for the given scope and returns
+// the token name on success.
+func (a *Authenticator) Authorize(r *http.Request, scope Scope) (string, error) {
+	raw, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || strings.TrimSpace(raw) == "" {
+		return "", ErrNoToken
+	}
+	sum := sha256.Sum256([]byte(strings.TrimSpace(raw)))
+
+	for _, t := range a.tokens {
+		if subtle.ConstantTimeCompare(sum[:], t.hash) != 1 {
+			continue
+		}
+		for _, sc := range t.Scopes {
+			if sc == scope {
+				return t.Name, nil
+			}
+		}
+		return t.Name, ErrForbidden
+	}
+	return "", ErrInvalidToken
+}
+
+// HashToken returns the hex-encoded SHA-256 of a raw token, as stored in config.
+func HashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateToken returns a new random token.
+func GenerateToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	t.Setenv(EnvTokens, "ops:"+HashToken("ops-secret")+":fetch,sources; mod:"+HashToken("mod-secret")+":moderation")
+	a, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		header   string
+		scope    Scope
+		wantName string
+		wantErr  error
+	}{
+		{"", ScopeFetch, "", ErrNoToken},
+		{"Basic b3BzOnNlY3JldA==", ScopeFetch, "", ErrNoToken},
+		{"Bearer   ", ScopeFetch, "", ErrNoToken},
+		{"Bearer not-a-token", ScopeFetch, "", ErrInvalidToken},
+		{"Bearer " + HashToken("ops-secret"), ScopeFetch, "", ErrInvalidToken}, // the hash is not the secret
+		{"Bearer ops-secret", ScopeModeration, "ops", ErrForbidden},
+		{"Bearer ops-secret", ScopeFetch, "ops", nil},
+		{"Bearer ops-secret ", ScopeSources, "ops", nil},
+		{"Bearer mod-secret", ScopeModeration, "mod", nil},
+		{"Bearer mod-secret", ScopeFetch, "mod", ErrForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/news/fetch", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		name, err := a.Authorize(r, tt.scope)
+		if name != tt.wantName || !errors.Is(err, tt.wantErr) {
+			t.Errorf("%q for %s: %q, %v; want %q, %v", tt.header, tt.scope, name, err, tt.wantName, tt.wantErr)
+		}
+	}
+}
+
+func TestLoadTokensErrors(t *testing.T) {
+	hash := HashToken("secret")
+	tests := []struct {
+		env  string
+		want string // "" means valid
+	}{
+		{"ops:" + hash + ":fetch", ""},
+		{"ops:sha256:" + hash + ":fetch", `bad token entry`}, // the prefix is only allowed in files
+		{"ops:" + hash, `bad token entry "ops:` + hash + `", want name:hash:scopes`},
+		{"ops:abc123:fetch", `token "ops": hash must be a hex-encoded SHA-256`},
+		{"ops:" + hash[:60] + ":fetch", `token "ops": hash must be a hex-encoded SHA-256`},
+		{"ops:" + hash + ":fetch,admin", `token "ops": unknown scope "admin"`},
+		{"ops:" + hash + ":", `token "ops": at least one scope is required`},
+		{":" + hash + ":fetch", `token "": name is required`},
+		{"ops:" + hash + ":fetch;ops:" + HashToken("other") + ":sources", `token "ops": duplicate name`},
+	}
+	for _, tt := range tests {
+		t.Setenv(EnvTokens, tt.env)
+		_, err := Load("")
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%q: %v", tt.env, err)
+		case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+			t.Errorf("%q: error %v, want %q", tt.env, err, tt.want)
+		}
+	}
+}
@@ -2,7 +2,10 @@ package database
 
 import (
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 	"top-ai-news/internal/model"
 
@@ -13,8 +16,11 @@ type DB struct {
 	conn *sql.DB
 }
 
+// ErrEditionLocked is returned by SaveRanking for a date whose edition is locked.
+var ErrEditionLocked = errors.New("edition is locked")
+
 func New(dbPath string) (*DB, error) {
-	conn, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(wal)&_pragma=busy_timeout(5000)")
+	conn, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(wal)&_pragma=busy_timeout(5000)&_time_format=sqlite")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
@@ -29,7 +35,17 @@ func (db *DB) Close() error {
 	return db.conn.Close()
 }
 
+// migrate creates the version 0 schema on a new (or pre-versioning) database
+// and then applies the versioned migrations.
 func (db *DB) migrate() error {
+	var version int
+	if err := db.conn.QueryRow(`PRAGMA user_version`).Scan(&version); err != nil {
+		return err
+	}
+	if version > 0 {
+		return db.upgrade(version)
+	}
+
 	queries := []string{
 		`CREATE TABLE IF NOT EXISTS news (
 			id INTEGER PRIMARY KEY AUTOINCREMENT,
@@ -50,6 +66,56 @@ func (db *DB) migrate() error {
 			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
 			FOREIGN KEY (news_id) REFERENCES news(id) ON DELETE CASCADE
 		)`,
+		`CREATE TABLE IF NOT EXISTS feed_cache (
+			feed_url TEXT PRIMARY KEY,
+			etag TEXT DEFAULT '',
+			last_modified TEXT DEFAULT '',
+			body BLOB,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS feed_breakers (
+			feed_url TEXT PRIMARY KEY,
+			source_name TEXT DEFAULT '',
+			failures INTEGER DEFAULT 0,
+			last_error TEXT DEFAULT '',
+			opened_at DATETIME,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS fetch_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			date TEXT NOT NULL,
+			trigger TEXT NOT NULL,
+			status TEXT NOT NULL DEFAULT 'running',
+			stored INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			started_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			finished_at DATETIME
+		)`,
+		`CREATE TABLE IF NOT EXISTS fetch_run_sources (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			run_id INTEGER NOT NULL,
+			source_name TEXT NOT NULL,
+			feed_url TEXT DEFAULT '',
+			status TEXT NOT NULL,
+			http_status INTEGER DEFAULT 0,
+			items INTEGER DEFAULT 0,
+			kept INTEGER DEFAULT 0,
+			error TEXT DEFAULT '',
+			duration_ms INTEGER DEFAULT 0,
+			FOREIGN KEY (run_id) REFERENCES fetch_runs(id) ON DELETE CASCADE
+		)`,
+		`CREATE TABLE IF NOT EXISTS schedule_state (
+			name TEXT PRIMARY KEY,
+			last_slot DATETIME NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS editions (
+			date TEXT PRIMARY KEY,
+			state TEXT NOT NULL DEFAULT 'draft',
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_fetch_run_sources_run ON fetch_run_sources(run_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_fetch_run_sources_source ON fetch_run_sources(source_name)`,
 		`CREATE INDEX IF NOT EXISTS idx_news_date_category ON news(publish_date, category)`,
 		`CREATE INDEX IF NOT EXISTS idx_comments_news_id ON comments(news_id)`,
 	}
@@ -58,13 +124,16 @@ func (db *DB) migrate() error {
 			return fmt.Errorf("exec %q: %w", q[:40], err)
 		}
 	}
-	return nil
+	return db.upgrade(version)
 }
 
+// GetNewsByDate returns the active ranking of date, joined with its articles.
 func (db *DB) GetNewsByDate(date string) ([]model.News, error) {
 	rows, err := db.conn.Query(
-		`SELECT id, title, summary, source_url, source_name, category, publish_date, rank, created_at
-		 FROM news WHERE publish_date = ? ORDER BY category, rank`,
+		`SELECT a.id, a.title, a.summary, a.source_url, a.canonical_url, a.source_name, a.published_at,
+		        r.category, r.date, r.rank, r.score, r.status, a.created_at
+		 FROM rankings r JOIN articles a ON a.id = r.article_id
+		 WHERE r.date = ? AND r.status = 'active' ORDER BY r.category, r.rank`,
 		date,
 	)
 	if err != nil {
@@ -75,25 +144,179 @@ func (db *DB) GetNewsByDate(date string) ([]model.News, error) {
 	var news []model.News
 	for rows.Next() {
 		var n model.News
-		if err := rows.Scan(&n.ID, &n.Title, &n.Summary, &n.SourceURL, &n.SourceName,
-			&n.Category, &n.PublishDate, &n.Rank, &n.CreatedAt); err != nil {
+		var published sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Title, &n.Summary, &n.SourceURL, &n.CanonicalURL, &n.SourceName, &published,
+			&n.Category, &n.PublishDate, &n.Rank, &n.Score, &n.Status, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+		if published.Valid {
+			n.PublishedAt = published.Time
+		}
+		news = append(news, n)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := db.attachCoverage(date, news); err != nil {
+		return nil, err
+	}
+	return news, db.attachLinks(news)
+}
+
+// attachCoverage fills in AlsoCoveredBy from the story clusters of date.
+func (db *DB) attachCoverage(date string, news []model.News) error {
+	if len(news) == 0 {
+		return nil
+	}
+	rows, err := db.conn.Query(
+		`SELECT s.article_id, a.id, a.title, a.source_url, a.source_name
+		 FROM story_members s JOIN articles a ON a.id = s.member_id
+		 WHERE s.date = ? ORDER BY s.article_id, a.source_name`,
+		date,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	byArticle := make(map[int64][]model.Coverage)
+	for rows.Next() {
+		var articleID int64
+		var c model.Coverage
+		if err := rows.Scan(&articleID, &c.ID, &c.Title, &c.SourceURL, &c.SourceName); err != nil {
+			return err
+		}
+		byArticle[articleID] = append(byArticle[articleID], c)
+	}
+	for i := range news {
+		news[i].AlsoCoveredBy = byArticle[news[i].ID]
+	}
+	return rows.Err()
+}
+
+// attachCoverageByDate fills in AlsoCoveredBy for news from several dates.
+func (db *DB) attachCoverageByDate(news []model.News) error {
+	byDate := make(map[string][]int)
+	for i := range news {
+		byDate[news[i].PublishDate] = append(byDate[news[i].PublishDate], i)
+	}
+	for date, idx := range byDate {
+		day := make([]model.News, len(idx))
+		for j, i := range idx {
+			day[j] = news[i]
+		}
+		if err := db.attachCoverage(date, day); err != nil {
+			return err
+		}
+		for j, i := range idx {
+			news[i].AlsoCoveredBy = day[j].AlsoCoveredBy
+		}
+	}
+	return nil
+}
+
+// attachLinks fills in the tags and entities of news.
+func (db *DB) attachLinks(news []model.News) error {
+	if len(news) == 0 {
+		return nil
+	}
+	ids := make([]interface{}, len(news))
+	for i := range news {
+		ids[i] = news[i].ID
+	}
+	tags, err := db.links("article_tags", "tag", ids)
+	if err != nil {
+		return err
+	}
+	entities, err := db.links("article_entities", "entity", ids)
+	if err != nil {
+		return err
+	}
+	for i := range news {
+		news[i].Tags = tags[news[i].ID]
+		news[i].Entities = entities[news[i].ID]
+	}
+	return nil
+}
+
+// links returns the values of column in the link table for the articles ids,
+// by article.
+func (db *DB) links(table, column string, ids []interface{}) (map[int64][]string, error) {
+	rows, err := db.conn.Query(fmt.Sprintf(
+		`SELECT article_id, %s FROM %s WHERE article_id IN (%s) ORDER BY article_id, %[1]s`,
+		column, table, strings.TrimSuffix(strings.Repeat("?,", len(ids)), ",")), ids...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byArticle := make(map[int64][]string)
+	for rows.Next() {
+		var articleID int64
+		var v string
+		if err := rows.Scan(&articleID, &v); err != nil {
 			return nil, err
 		}
+		byArticle[articleID] = append(byArticle[articleID], v)
+	}
+	return byArticle, rows.Err()
+}
+
+// GetNewsByEntity returns the ranked news that mention entity, newest first,
+// each at the latest date it was ranked on, and the total number of them.
+func (db *DB) GetNewsByEntity(entity string, limit, offset int) ([]model.News, int, error) {
+	const latest = `FROM article_entities e
+		 JOIN rankings r ON r.article_id = e.article_id AND r.status = 'active'
+		 JOIN articles a ON a.id = e.article_id
+		 WHERE e.entity = ? AND r.date = (
+		   SELECT MAX(date) FROM rankings WHERE article_id = e.article_id AND status = 'active')`
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) `+latest, entity).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.title, a.summary, a.source_url, a.canonical_url, a.source_name, a.published_at,
+		        r.category, r.date, r.rank, r.score, r.status, a.created_at
+		 `+latest+` ORDER BY r.date DESC, r.category, r.rank LIMIT ? OFFSET ?`,
+		entity, limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var news []model.News
+	for rows.Next() {
+		var n model.News
+		var published sql.NullTime
+		if err := rows.Scan(&n.ID, &n.Title, &n.Summary, &n.SourceURL, &n.CanonicalURL, &n.SourceName, &published,
+			&n.Category, &n.PublishDate, &n.Rank, &n.Score, &n.Status, &n.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		if published.Valid {
+			n.PublishedAt = published.Time
+		}
 		news = append(news, n)
 	}
-	return news, rows.Err()
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := db.attachCoverageByDate(news); err != nil {
+		return nil, 0, err
+	}
+	return news, total, db.attachLinks(news)
 }
 
 func (db *DB) HasNewsForDate(date string) (bool, error) {
 	var count int
-	err := db.conn.QueryRow(`SELECT COUNT(*) FROM news WHERE publish_date = ?`, date).Scan(&count)
+	err := db.conn.QueryRow(`SELECT COUNT(*) FROM rankings WHERE date = ? AND status = 'active'`, date).Scan(&count)
 	return count > 0, err
 }
 
 func (db *DB) GetPrevDate(date string) (string, bool) {
 	var prev string
 	err := db.conn.QueryRow(
-		`SELECT publish_date FROM news WHERE publish_date < ? GROUP BY publish_date ORDER BY publish_date DESC LIMIT 1`,
+		`SELECT date FROM rankings WHERE date < ? AND status = 'active' GROUP BY date ORDER BY date DESC LIMIT 1`,
 		date,
 	).Scan(&prev)
 	if err != nil {
@@ -105,7 +328,7 @@ func (db *DB) GetPrevDate(date string) (string, bool) {
 func (db *DB) GetNextDate(date string) (string, bool) {
 	var next string
 	err := db.conn.QueryRow(
-		`SELECT publish_date FROM news WHERE publish_date > ? GROUP BY publish_date ORDER BY publish_date ASC LIMIT 1`,
+		`SELECT date FROM rankings WHERE date > ? AND status = 'active' GROUP BY date ORDER BY date ASC LIMIT 1`,
 		date,
 	).Scan(&next)
 	if err != nil {
@@ -114,32 +337,353 @@ func (db *DB) GetNextDate(date string) (string, bool) {
 	return next, true
 }
 
+// GetLatestDate returns the most recent date with news, or "" if there is none.
 func (db *DB) GetLatestDate() (string, error) {
 	var date string
 	err := db.conn.QueryRow(
-		`SELECT publish_date FROM news ORDER BY publish_date DESC LIMIT 1`,
+		`SELECT date FROM rankings WHERE status = 'active' ORDER BY date DESC LIMIT 1`,
 	).Scan(&date)
 	if err == sql.ErrNoRows {
-		return time.Now().Format("2006-01-02"), nil
+		return "", nil
 	}
 	return date, err
 }
 
-func (db *DB) InsertNews(n model.News) (int64, error) {
-	result, err := db.conn.Exec(
-		`INSERT INTO news (title, summary, source_url, source_name, category, publish_date, rank)
-		 VALUES (?, ?, ?, ?, ?, ?, ?)`,
-		n.Title, n.Summary, n.SourceURL, n.SourceName, n.Category, n.PublishDate, n.Rank,
+// SaveRanking stores the candidates of a run for date and makes the ranked
+// ones (Rank > 0) the active ranking. Articles are upserted by canonical URL,
+// so an article keeps one ID (and one comment thread) across refreshes and
+// days. Active rankings of date that are no longer ranked are marked dropped.
+// Candidates repeating a canonical URL are ignored, ranked ones being kept
+// over unranked ones, and the ranks of a category close up over the ignored
+// ones. Candidates are only recorded when runID is set; only the pools of the
+// latest CandidateRuns runs of date are kept. Unless force is set, a locked
+// edition is left untouched and ErrEditionLocked is returned; the state is
+// checked in the same transaction, so an edition locked while the run was
+// fetching is kept.
+func (db *DB) SaveRanking(runID int64, date string, cands []model.Candidate, force bool) (model.RankingChange, error) {
+	var change model.RankingChange
+	tx, err := db.conn.Begin()
+	if err != nil {
+		return change, err
+	}
+	defer tx.Rollback()
+
+	if !force {
+		var state string
+		err := tx.QueryRow(`SELECT state FROM editions WHERE date = ?`, date).Scan(&state)
+		if err != nil && err != sql.ErrNoRows {
+			return change, err
+		}
+		if state == "locked" {
+			return change, ErrEditionLocked
+		}
+	}
+
+	existing := make(map[int64]bool)
+	rows, err := tx.Query(`SELECT article_id FROM rankings WHERE date = ?`, date)
+	if err != nil {
+		return change, err
+	}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return change, err
+		}
+		existing[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return change, err
+	}
+
+	// The date's story clusters are replaced by the ones of this run.
+	if _, err := tx.Exec(`DELETE FROM story_members WHERE date = ?`, date); err != nil {
+		return change, err
+	}
+
+	// An article has one ranking per date: the first category that ranks it
+	// wins, and the later ranks of the other category are moved up.
+	ordered := make([]model.Candidate, 0, len(cands))
+	for _, c := range cands {
+		if c.News.Rank > 0 {
+			ordered = append(ordered, c)
+		}
+	}
+	for _, c := range cands {
+		if c.News.Rank == 0 {
+			ordered = append(ordered, c)
+		}
+	}
+	seen := make(map[int64]bool)
+	kept := make(map[int64]bool)
+	ranks := make(map[string]int)
+	for _, c := range ordered {
+		n := c.News
+		id, err := upsertArticle(tx, n)
+		if err != nil {
+			return change, err
+		}
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if n.Rank > 0 {
+			ranks[n.Category]++
+			n.Rank = ranks[n.Category]
+		}
+
+		for _, m := range c.Members {
+			memberID, err := upsertArticle(tx, m)
+			if err != nil {
+				return change, err
+			}
+			if memberID == id {
+				continue
+			}
+			if _, err := tx.Exec(
+				`INSERT OR IGNORE INTO story_members (date, article_id, member_id) VALUES (?, ?, ?)`,
+				date, id, memberID,
+			); err != nil {
+				return change, err
+			}
+		}
+
+		if runID != 0 {
+			keywords, _ := json.Marshal(c.Keywords)
+			if c.Keywords == nil {
+				keywords = []byte("[]")
+			}
+			if _, err := tx.Exec(
+				`INSERT INTO candidates (run_id, date, category, article_id, position, rank, score,
+				   timeliness, relevance, source_weight, buzz, hours_ago, keywords, hits, weight_default, sources)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				runID, date, n.Category, id, c.Position, n.Rank, n.Score,
+				c.Timeliness, c.Relevance, c.SourceWeight, c.Buzz, c.HoursAgo, string(keywords), c.Hits, c.WeightDefault, c.Sources,
+			); err != nil {
+				return change, err
+			}
+		}
+		if n.Rank == 0 {
+			continue
+		}
+		kept[id] = true
+
+		if _, err := tx.Exec(
+			`INSERT INTO rankings (date, category, rank, score, article_id, status, updated_at)
+			 VALUES (?, ?, ?, ?, ?, 'active', CURRENT_TIMESTAMP)
+			 ON CONFLICT(date, article_id) DO UPDATE SET
+			   category = excluded.category,
+			   rank = excluded.rank,
+			   score = excluded.score,
+			   status = 'active',
+			   updated_at = excluded.updated_at`,
+			date, n.Category, n.Rank, n.Score, id,
+		); err != nil {
+			return change, err
+		}
+		if existing[id] {
+			change.Updated++
+		} else {
+			change.Inserted++
+		}
+	}
+
+	rows, err = tx.Query(`SELECT article_id FROM rankings WHERE date = ? AND status = 'active'`, date)
+	if err != nil {
+		return change, err
+	}
+	var drop []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return change, err
+		}
+		if !kept[id] {
+			drop = append(drop, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return change, err
+	}
+	for _, id := range drop {
+		if _, err := tx.Exec(
+			`UPDATE rankings SET status = 'dropped', updated_at = CURRENT_TIMESTAMP WHERE date = ? AND article_id = ?`,
+			date, id,
+		); err != nil {
+			return change, err
+		}
+		change.Dropped++
+	}
+
+	if runID != 0 {
+		if err := pruneCandidates(tx, date); err != nil {
+			return change, err
+		}
+	}
+	return change, tx.Commit()
+}
+
+// CandidateRuns is the number of runs whose candidates are kept for a date.
+// Older pools are deleted when a run stores a new one, so scheduled refreshes
+// do not grow the candidates table without bound.
+const CandidateRuns = 10
+
+// pruneCandidates deletes the candidates of date, or of every date if it is
+// empty, except those of the latest CandidateRuns runs.
+func pruneCandidates(tx *sql.Tx, date string) error {
+	_, err := tx.Exec(
+		`DELETE FROM candidates WHERE (date, run_id) IN (
+		   SELECT date, run_id FROM (
+		     SELECT date, run_id, ROW_NUMBER() OVER (PARTITION BY date ORDER BY run_id DESC) AS n
+		     FROM (SELECT DISTINCT date, run_id FROM candidates WHERE ? = '' OR date = ?)
+		   ) WHERE n > ?
+		 )`,
+		date, date, CandidateRuns,
+	)
+	return err
+}
+
+// LatestCandidateRun returns the most recent run that stored candidates for
+// date, or 0 if there is none.
+func (db *DB) LatestCandidateRun(date string) (int64, error) {
+	var runID sql.NullInt64
+	err := db.conn.QueryRow(`SELECT MAX(run_id) FROM candidates WHERE date = ?`, date).Scan(&runID)
+	return runID.Int64, err
+}
+
+// candidateColumns are the columns read by scanCandidate.
+const candidateColumns = `a.id, a.title, a.summary, a.source_url, a.canonical_url, a.source_name, a.published_at, a.created_at,
+	c.category, c.date, c.rank, c.score, c.run_id, c.position, c.timeliness, c.relevance, c.source_weight, c.buzz,
+	c.hours_ago, c.keywords, c.hits, c.weight_default, c.sources`
+
+// ListCandidates returns the candidates a run stored for date, highest score
+// first. An empty category means all categories.
+func (db *DB) ListCandidates(runID int64, date, category string) ([]model.Candidate, error) {
+	rows, err := db.conn.Query(
+		`SELECT `+candidateColumns+`
+		 FROM candidates c JOIN articles a ON a.id = c.article_id
+		 WHERE c.run_id = ? AND c.date = ? AND (? = '' OR c.category = ?)
+		 ORDER BY c.score DESC, c.position`,
+		runID, date, category, category,
 	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cands []model.Candidate
+	for rows.Next() {
+		c, err := scanCandidate(rows)
+		if err != nil {
+			return nil, err
+		}
+		cands = append(cands, *c)
+	}
+	return cands, rows.Err()
+}
+
+// GetCandidate returns the latest candidate record of an article, restricted
+// to date unless it is empty, together with the size of that run's pool in
+// the article's category. It returns nil if the article was never a candidate.
+func (db *DB) GetCandidate(articleID int64, date string) (*model.Candidate, int, error) {
+	c, err := scanCandidate(db.conn.QueryRow(
+		`SELECT `+candidateColumns+`
+		 FROM candidates c JOIN articles a ON a.id = c.article_id
+		 WHERE c.article_id = ? AND (? = '' OR c.date = ?)
+		 ORDER BY c.run_id DESC, c.date DESC LIMIT 1`,
+		articleID, date, date,
+	))
+	if err == sql.ErrNoRows {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var total int
+	err = db.conn.QueryRow(
+		`SELECT COUNT(*) FROM candidates WHERE run_id = ? AND date = ? AND category = ?`,
+		c.RunID, c.PublishDate, c.Category,
+	).Scan(&total)
+	return c, total, err
+}
+
+func scanCandidate(row interface{ Scan(...any) error }) (*model.Candidate, error) {
+	var c model.Candidate
+	var published sql.NullTime
+	var keywords string
+	if err := row.Scan(&c.ID, &c.Title, &c.Summary, &c.SourceURL, &c.CanonicalURL, &c.SourceName, &published, &c.CreatedAt,
+		&c.Category, &c.PublishDate, &c.Rank, &c.Score, &c.RunID, &c.Position, &c.Timeliness, &c.Relevance, &c.SourceWeight, &c.Buzz,
+		&c.HoursAgo, &keywords, &c.Hits, &c.WeightDefault, &c.Sources); err != nil {
+		return nil, err
+	}
+	if published.Valid {
+		c.PublishedAt = published.Time
+	}
+	if err := json.Unmarshal([]byte(keywords), &c.Keywords); err != nil {
+		return nil, fmt.Errorf("candidate %d keywords: %w", c.ID, err)
+	}
+	if c.Keywords == nil {
+		c.Keywords = []string{}
+	}
+	c.Status = model.NewsCandidate
+	if c.Rank > 0 {
+		c.Status = model.NewsActive
+	}
+	return &c, nil
+}
+
+// upsertArticle stores the article part of n, replacing its tags, entities
+// and search index entry, and returns its ID.
+func upsertArticle(tx *sql.Tx, n model.News) (int64, error) {
+	var published interface{}
+	if !n.PublishedAt.IsZero() {
+		published = n.PublishedAt.UTC()
+	}
+	var id int64
+	err := tx.QueryRow(
+		`INSERT INTO articles (canonical_url, title, summary, source_url, source_name, published_at)
+		 VALUES (?, ?, ?, ?, ?, ?)
+		 ON CONFLICT(canonical_url) DO UPDATE SET
+		   title = excluded.title,
+		   summary = excluded.summary,
+		   source_url = excluded.source_url,
+		   source_name = excluded.source_name,
+		   published_at = COALESCE(excluded.published_at, articles.published_at),
+		   updated_at = CURRENT_TIMESTAMP
+		 RETURNING id`,
+		n.CanonicalURL, n.Title, n.Summary, n.SourceURL, n.SourceName, published,
+	).Scan(&id)
 	if err != nil {
 		return 0, err
 	}
-	return result.LastInsertId()
+	if err := setLinks(tx, "article_tags", "tag", id, n.Tags); err != nil {
+		return 0, err
+	}
+	if err := setLinks(tx, "article_entities", "entity", id, n.Entities); err != nil {
+		return 0, err
+	}
+	if err := indexArticle(tx, id, n.Title, n.Summary); err != nil {
+		return 0, err
+	}
+	return id, nil
 }
 
-func (db *DB) DeleteNewsByDate(date string) error {
-	_, err := db.conn.Exec(`DELETE FROM news WHERE publish_date = ?`, date)
-	return err
+// setLinks replaces the values of column in the link table for article id.
+func setLinks(tx *sql.Tx, table, column string, id int64, values []string) error {
+	if _, err := tx.Exec(fmt.Sprintf(`DELETE FROM %s WHERE article_id = ?`, table), id); err != nil {
+		return err
+	}
+	insert := fmt.Sprintf(`INSERT OR IGNORE INTO %s (article_id, %s) VALUES (?, ?)`, table, column)
+	for _, v := range values {
+		if _, err := tx.Exec(insert, id, v); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (db *DB) GetCommentsByNewsID(newsID int64) ([]model.Comment, error) {
@@ -178,6 +722,16 @@ func (db *DB) InsertComment(newsID int64, author, content string) (int64, error)
 	return result.LastInsertId()
 }
 
+// DeleteComment removes a comment and reports whether it existed.
+func (db *DB) DeleteComment(id int64) (bool, error) {
+	result, err := db.conn.Exec(`DELETE FROM comments WHERE id = ?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
 func (db *DB) GetCommentCount(newsID int64) (int, error) {
 	var count int
 	err := db.conn.QueryRow(`SELECT COUNT(*) FROM comments WHERE news_id = ?`, newsID).Scan(&count)
@@ -186,7 +740,7 @@ func (db *DB) GetCommentCount(newsID int64) (int, error) {
 
 func (db *DB) GetAllDates() ([]string, error) {
 	rows, err := db.conn.Query(
-		`SELECT DISTINCT publish_date FROM news ORDER BY publish_date DESC`,
+		`SELECT DISTINCT date FROM rankings WHERE status = 'active' ORDER BY date DESC`,
 	)
 	if err != nil {
 		return nil, err
@@ -203,3 +757,358 @@ func (db *DB) GetAllDates() ([]string, error) {
 	}
 	return dates, rows.Err()
 }
+
+// GetFeedCache returns the cached validators for a feed, or nil if none are stored.
+func (db *DB) GetFeedCache(feedURL string) (*model.FeedCache, error) {
+	c := model.FeedCache{FeedURL: feedURL}
+	err := db.conn.QueryRow(
+		`SELECT etag, last_modified, body, updated_at FROM feed_cache WHERE feed_url = ?`,
+		feedURL,
+	).Scan(&c.ETag, &c.LastModified, &c.Body, &c.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (db *DB) SaveFeedCache(c model.FeedCache) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO feed_cache (feed_url, etag, last_modified, body, updated_at)
+		 VALUES (?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(feed_url) DO UPDATE SET
+		   etag = excluded.etag,
+		   last_modified = excluded.last_modified,
+		   body = excluded.body,
+		   updated_at = excluded.updated_at`,
+		c.FeedURL, c.ETag, c.LastModified, c.Body,
+	)
+	return err
+}
+
+// GetEditionState returns the state of a date's edition, or "" if it has none.
+func (db *DB) GetEditionState(date string) (string, error) {
+	var state string
+	err := db.conn.QueryRow(`SELECT state FROM editions WHERE date = ?`, date).Scan(&state)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return state, err
+}
+
+// EnsureEdition creates a draft edition for date unless one exists.
+func (db *DB) EnsureEdition(date string) error {
+	_, err := db.conn.Exec(`INSERT OR IGNORE INTO editions (date, state) VALUES (?, 'draft')`, date)
+	return err
+}
+
+// SetEditionState sets the state of a date's edition, creating it if needed.
+func (db *DB) SetEditionState(date, state string) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO editions (date, state, updated_at)
+		 VALUES (?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(date) DO UPDATE SET
+		   state = excluded.state,
+		   updated_at = excluded.updated_at`,
+		date, state,
+	)
+	return err
+}
+
+// ListEditions returns the most recent editions, newest first.
+func (db *DB) ListEditions(limit int) ([]model.Edition, error) {
+	rows, err := db.conn.Query(
+		`SELECT date, state, updated_at FROM editions ORDER BY date DESC LIMIT ?`, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var editions []model.Edition
+	for rows.Next() {
+		var e model.Edition
+		if err := rows.Scan(&e.Date, &e.State, &e.UpdatedAt); err != nil {
+			return nil, err
+		}
+		editions = append(editions, e)
+	}
+	return editions, rows.Err()
+}
+
+// GetScheduleSlot returns the last slot a schedule completed, or nil if it has
+// never run.
+func (db *DB) GetScheduleSlot(name string) (*time.Time, error) {
+	var slot time.Time
+	err := db.conn.QueryRow(
+		`SELECT last_slot FROM schedule_state WHERE name = ?`, name,
+	).Scan(&slot)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &slot, nil
+}
+
+// SaveScheduleSlot records slot as the last completed slot of a schedule.
+func (db *DB) SaveScheduleSlot(name string, slot time.Time) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO schedule_state (name, last_slot, updated_at)
+		 VALUES (?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(name) DO UPDATE SET
+		   last_slot = excluded.last_slot,
+		   updated_at = excluded.updated_at`,
+		name, slot.UTC(),
+	)
+	return err
+}
+
+// GetFeedBreaker returns the breaker state of a feed, or nil if it has never failed.
+func (db *DB) GetFeedBreaker(feedURL string) (*model.FeedBreaker, error) {
+	b, err := scanFeedBreaker(db.conn.QueryRow(
+		`SELECT feed_url, source_name, failures, last_error, opened_at, updated_at
+		 FROM feed_breakers WHERE feed_url = ?`,
+		feedURL,
+	))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return b, err
+}
+
+func (db *DB) SaveFeedBreaker(b model.FeedBreaker) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO feed_breakers (feed_url, source_name, failures, last_error, opened_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
+		 ON CONFLICT(feed_url) DO UPDATE SET
+		   source_name = excluded.source_name,
+		   failures = excluded.failures,
+		   last_error = excluded.last_error,
+		   opened_at = excluded.opened_at,
+		   updated_at = excluded.updated_at`,
+		b.FeedURL, b.SourceName, b.Failures, b.LastError, b.OpenedAt,
+	)
+	return err
+}
+
+func (db *DB) ListFeedBreakers() ([]model.FeedBreaker, error) {
+	rows, err := db.conn.Query(
+		`SELECT feed_url, source_name, failures, last_error, opened_at, updated_at
+		 FROM feed_breakers ORDER BY failures DESC, source_name`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var breakers []model.FeedBreaker
+	for rows.Next() {
+		b, err := scanFeedBreaker(rows)
+		if err != nil {
+			return nil, err
+		}
+		breakers = append(breakers, *b)
+	}
+	return breakers, rows.Err()
+}
+
+func scanFeedBreaker(row interface{ Scan(...any) error }) (*model.FeedBreaker, error) {
+	var b model.FeedBreaker
+	var openedAt sql.NullTime
+	if err := row.Scan(&b.FeedURL, &b.SourceName, &b.Failures, &b.LastError, &openedAt, &b.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if openedAt.Valid {
+		b.OpenedAt = &openedAt.Time
+	}
+	return &b, nil
+}
+
+// CreateFetchRun records the start of a run; ranking is the JSON of the
+// ranking options it scores with.
+func (db *DB) CreateFetchRun(date, trigger, ranking string) (int64, error) {
+	result, err := db.conn.Exec(
+		`INSERT INTO fetch_runs (date, trigger, started_at, ranking) VALUES (?, ?, ?, ?)`,
+		date, trigger, time.Now().UTC(), ranking,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+// GetFetchRunRanking returns the ranking options JSON recorded for a run, or
+// "" for runs made before it was recorded.
+func (db *DB) GetFetchRunRanking(id int64) (string, error) {
+	var ranking string
+	err := db.conn.QueryRow(`SELECT ranking FROM fetch_runs WHERE id = ?`, id).Scan(&ranking)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return ranking, err
+}
+
+func (db *DB) FinishFetchRun(id int64, status string, stored int, errMsg string) error {
+	_, err := db.conn.Exec(
+		`UPDATE fetch_runs SET status = ?, stored = ?, error = ?, finished_at = ? WHERE id = ?`,
+		status, stored, errMsg, time.Now().UTC(), id,
+	)
+	return err
+}
+
+func (db *DB) InsertFetchRunSource(s model.FetchRunSource) error {
+	_, err := db.conn.Exec(
+		`INSERT INTO fetch_run_sources
+		   (run_id, source_name, feed_url, status, http_status, items, kept, error, duration_ms)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		s.RunID, s.SourceName, s.FeedURL, s.Status, s.HTTPStatus, s.Items, s.Kept, s.Error, s.DurationMS,
+	)
+	return err
+}
+
+// ListFetchRuns returns the most recent runs, newest first, with their per-source records.
+func (db *DB) ListFetchRuns(limit int) ([]model.FetchRun, error) {
+	rows, err := db.conn.Query(
+		`SELECT id, date, trigger, status, stored, error, started_at, finished_at
+		 FROM fetch_runs ORDER BY id DESC LIMIT ?`,
+		limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []model.FetchRun
+	for rows.Next() {
+		var r model.FetchRun
+		var finished sql.NullTime
+		if err := rows.Scan(&r.ID, &r.Date, &r.Trigger, &r.Status, &r.Stored, &r.Error,
+			&r.StartedAt, &finished); err != nil {
+			return nil, err
+		}
+		if finished.Valid {
+			r.FinishedAt = &finished.Time
+		}
+		runs = append(runs, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for i := range runs {
+		if runs[i].Sources, err = db.getFetchRunSources(runs[i].ID); err != nil {
+			return nil, err
+		}
+	}
+	return runs, nil
+}
+
+func (db *DB) getFetchRunSources(runID int64) ([]model.FetchRunSource, error) {
+	rows, err := db.conn.Query(
+		`SELECT run_id, source_name, feed_url, status, http_status, items, kept, error, duration_ms
+		 FROM fetch_run_sources WHERE run_id = ? ORDER BY source_name`,
+		runID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sources []model.FetchRunSource
+	for rows.Next() {
+		var s model.FetchRunSource
+		if err := rows.Scan(&s.RunID, &s.SourceName, &s.FeedURL, &s.Status, &s.HTTPStatus,
+			&s.Items, &s.Kept, &s.Error, &s.DurationMS); err != nil {
+			return nil, err
+		}
+		sources = append(sources, s)
+	}
+	return sources, rows.Err()
+}
+
+// GetSourceHealth aggregates per-source run records for runs started since the given time.
+func (db *DB) GetSourceHealth(since time.Time) ([]model.SourceHealth, error) {
+	rows, err := db.conn.Query(
+		`SELECT s.source_name, MAX(s.feed_url), COUNT(*),
+		   SUM(s.status IN ('ok', 'not_modified')),
+		   SUM(s.status = 'error'),
+		   SUM(s.status = 'skipped'),
+		   SUM(s.items), SUM(s.kept),
+		   MAX(r.started_at),
+		   MAX(CASE WHEN s.status IN ('ok', 'not_modified') THEN r.started_at END),
+		   MAX(CASE WHEN s.kept > 0 THEN r.started_at END)
+		 FROM fetch_run_sources s JOIN fetch_runs r ON r.id = s.run_id
+		 WHERE r.started_at >= ?
+		 GROUP BY s.source_name
+		 ORDER BY s.source_name`,
+		since.UTC(),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var health []model.SourceHealth
+	for rows.Next() {
+		var h model.SourceHealth
+		var lastRun, lastSuccess, lastKept sql.NullString
+		if err := rows.Scan(&h.SourceName, &h.FeedURL, &h.Runs, &h.OK, &h.Errors, &h.Skipped,
+			&h.Items, &h.Kept, &lastRun, &lastSuccess, &lastKept); err != nil {
+			return nil, err
+		}
+		h.LastRunAt = parseDBTime(lastRun.String)
+		h.LastSuccessAt = parseNullDBTime(lastSuccess)
+		h.LastKeptAt = parseNullDBTime(lastKept)
+		health = append(health, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Fill in the latest status and error of each source
+	for i := range health {
+		err := db.conn.QueryRow(
+			`SELECT s.status, s.error FROM fetch_run_sources s
+			 WHERE s.source_name = ? ORDER BY s.run_id DESC LIMIT 1`,
+			health[i].SourceName,
+		).Scan(&health[i].LastStatus, &health[i].LastError)
+		if err != nil && err != sql.ErrNoRows {
+			return nil, err
+		}
+	}
+	return health, nil
+}
+
+// dbTimeLayouts are the formats SQLite timestamps come back in when the
+// column type is lost (e.g. from aggregates): CURRENT_TIMESTAMP and the
+// driver's _time_format=sqlite layout.
+var dbTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05",
+}
+
+func parseDBTime(s string) time.Time {
+	for _, layout := range dbTimeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func parseNullDBTime(s sql.NullString) *time.Time {
+	if !s.Valid {
+		return nil
+	}
+	t := parseDBTime(s.String)
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"top-ai-news/internal/model"
+)
+
+func TestSaveRankingLocked(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cands := []model.Candidate{
+		{News: model.News{Title: "Locked story", CanonicalURL: "https://a.example/1",
+			Category: "global", PublishDate: "2026-02-01", Rank: 1}},
+	}
+	if err := db.SetEditionState("2026-02-01", "locked"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.SaveRanking(0, "2026-02-01", cands, false); !errors.Is(err, ErrEditionLocked) {
+		t.Fatalf("SaveRanking on a locked edition: %v, want ErrEditionLocked", err)
+	}
+	change, err := db.SaveRanking(0, "2026-02-01", cands, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if change.Inserted != 1 {
+		t.Errorf("forced save inserted %d, want 1", change.Inserted)
+	}
+}
+
+func TestSaveRankingPrunesCandidates(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cands := []model.Candidate{
+		{News: model.News{Title: "Story", CanonicalURL: "https://a.example/1",
+			Category: "global", PublishDate: "2026-02-01", Rank: 1}},
+	}
+	var runs []int64
+	for range CandidateRuns + 2 {
+		runID, err := db.CreateFetchRun("2026-02-01", "cli", "{}")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.SaveRanking(runID, "2026-02-01", cands, false); err != nil {
+			t.Fatal(err)
+		}
+		runs = append(runs, runID)
+	}
+
+	var kept int
+	if err := db.conn.QueryRow(`SELECT COUNT(DISTINCT run_id) FROM candidates`).Scan(&kept); err != nil {
+		t.Fatal(err)
+	}
+	if kept != CandidateRuns {
+		t.Errorf("candidates of %d runs kept, want %d", kept, CandidateRuns)
+	}
+	for i, runID := range runs {
+		got, err := db.ListCandidates(runID, "2026-02-01", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := i >= 2; (len(got) > 0) != want {
+			t.Errorf("run %d: %d candidates, want kept=%v", i, len(got), want)
+		}
+	}
+}
+
+func TestSaveRankingAcrossCategories(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	cand := func(url, category string, rank int) model.Candidate {
+		return model.Candidate{News: model.News{Title: url, CanonicalURL: url,
+			Category: category, PublishDate: "2026-02-01", Rank: rank}}
+	}
+	// Both categories rank https://a.example/shared; the domestic pool also
+	// holds https://a.example/both unranked, ahead of its global placement.
+	cands := []model.Candidate{
+		cand("https://a.example/d1", "domestic", 1),
+		cand("https://a.example/shared", "domestic", 2),
+		cand("https://a.example/d3", "domestic", 3),
+		cand("https://a.example/both", "domestic", 0),
+		cand("https://a.example/shared", "global", 1),
+		cand("https://a.example/both", "global", 2),
+		cand("https://a.example/g3", "global", 3),
+	}
+	if _, err := db.SaveRanking(0, "2026-02-01", cands, false); err != nil {
+		t.Fatal(err)
+	}
+	news, err := db.GetNewsByDate("2026-02-01")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, n := range news {
+		got = append(got, fmt.Sprintf("%s %d %s", n.Category, n.Rank, n.Title))
+	}
+	want := []string{
+		"domestic 1 https://a.example/d1",
+		"domestic 2 https://a.example/shared",
+		"domestic 3 https://a.example/d3",
+		"global 1 https://a.example/both",
+		"global 2 https://a.example/g3",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("ranking:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,293 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"top-ai-news/internal/urlnorm"
+)
+
+// migrations upgrade the schema created by migrate. Entry i moves the database
+// from user_version i to i+1; append new steps, never edit applied ones.
+var migrations = []func(tx *sql.Tx) error{
+	// 1: keep news rows across refreshes. Rows are matched by canonical URL and
+	// dropped articles are marked instead of deleted.
+	func(tx *sql.Tx) error {
+		if err := execAll(tx,
+			`ALTER TABLE news ADD COLUMN canonical_url TEXT NOT NULL DEFAULT ''`,
+			`ALTER TABLE news ADD COLUMN status TEXT NOT NULL DEFAULT 'active'`,
+			`ALTER TABLE news ADD COLUMN updated_at DATETIME`,
+		); err != nil {
+			return err
+		}
+		if err := canonicalizeNews(tx); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`CREATE INDEX IF NOT EXISTS idx_news_date_url ON news(publish_date, canonical_url)`)
+		return err
+	},
+
+	// 2: split news into articles (one row per canonical URL) and rankings (an
+	// article's placement on a date). The first news row of each URL keeps its
+	// ID as the article ID and comments on its duplicates are moved onto it.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`UPDATE news SET canonical_url = 'news:' || id WHERE canonical_url = ''`,
+			`CREATE TABLE articles (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				canonical_url TEXT NOT NULL UNIQUE,
+				title TEXT NOT NULL,
+				summary TEXT DEFAULT '',
+				source_url TEXT DEFAULT '',
+				source_name TEXT DEFAULT '',
+				published_at DATETIME,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+			)`,
+			`CREATE TABLE rankings (
+				date TEXT NOT NULL,
+				category TEXT NOT NULL CHECK(category IN ('domestic', 'global')),
+				rank INTEGER NOT NULL DEFAULT 0,
+				score REAL NOT NULL DEFAULT 0,
+				article_id INTEGER NOT NULL,
+				status TEXT NOT NULL DEFAULT 'active',
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (date, article_id),
+				FOREIGN KEY (article_id) REFERENCES articles(id)
+			)`,
+			`CREATE INDEX idx_rankings_date ON rankings(date, status, category, rank)`,
+			`CREATE TEMP TABLE news_article AS
+				SELECT n.id AS news_id, (SELECT MIN(m.id) FROM news m WHERE m.canonical_url = n.canonical_url) AS article_id
+				FROM news n`,
+			`INSERT INTO articles (id, canonical_url, title, summary, source_url, source_name, created_at, updated_at)
+				SELECT n.id, n.canonical_url, n.title, n.summary, n.source_url, n.source_name, n.created_at, COALESCE(n.updated_at, n.created_at)
+				FROM news n JOIN news_article na ON na.news_id = n.id AND na.article_id = n.id`,
+			`INSERT OR IGNORE INTO rankings (date, category, rank, article_id, status, updated_at)
+				SELECT n.publish_date, n.category, n.rank, na.article_id, n.status, COALESCE(n.updated_at, n.created_at)
+				FROM news n JOIN news_article na ON na.news_id = n.id
+				ORDER BY n.status, n.id`,
+			`CREATE TABLE comments_new (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				news_id INTEGER NOT NULL,
+				author TEXT DEFAULT '匿名',
+				content TEXT NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				FOREIGN KEY (news_id) REFERENCES articles(id) ON DELETE CASCADE
+			)`,
+			`INSERT INTO comments_new (id, news_id, author, content, created_at)
+				SELECT c.id, COALESCE(na.article_id, c.news_id), c.author, c.content, c.created_at
+				FROM comments c LEFT JOIN news_article na ON na.news_id = c.news_id`,
+			`DROP TABLE comments`,
+			`ALTER TABLE comments_new RENAME TO comments`,
+			`CREATE INDEX idx_comments_news_id ON comments(news_id)`,
+			`DROP TABLE news_article`,
+			`DROP TABLE news`,
+		)
+	},
+
+	// 3: keep every deduplicated candidate of a run with its score components.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE candidates (
+				run_id INTEGER NOT NULL,
+				date TEXT NOT NULL,
+				category TEXT NOT NULL,
+				article_id INTEGER NOT NULL,
+				position INTEGER NOT NULL,
+				rank INTEGER NOT NULL DEFAULT 0,
+				score REAL NOT NULL,
+				timeliness REAL NOT NULL,
+				relevance REAL NOT NULL,
+				source_weight REAL NOT NULL,
+				created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (run_id, date, article_id),
+				FOREIGN KEY (article_id) REFERENCES articles(id)
+			)`,
+			`CREATE INDEX idx_candidates_date ON candidates(date, run_id)`,
+		)
+	},
+
+	// 4: record what is needed to explain a candidate's score.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE candidates ADD COLUMN hours_ago REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE candidates ADD COLUMN keywords TEXT NOT NULL DEFAULT '[]'`,
+			`ALTER TABLE candidates ADD COLUMN weight_default INTEGER NOT NULL DEFAULT 0`,
+			`CREATE INDEX idx_candidates_article ON candidates(article_id, run_id)`,
+		)
+	},
+
+	// 5: record the ranking strategy and parameters each run scored with, so
+	// its candidates can be explained and compared against other strategies.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE fetch_runs ADD COLUMN ranking TEXT NOT NULL DEFAULT ''`,
+		)
+	},
+
+	// 6: keyword terms carry weights, so relevance is computed from the sum of
+	// the matched weights. Earlier terms all weighed 1.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`ALTER TABLE candidates ADD COLUMN hits REAL NOT NULL DEFAULT 0`,
+			`UPDATE candidates SET hits = json_array_length(keywords)`,
+		)
+	},
+
+	// 7: group articles about the same story. The best-scored article of a
+	// story represents it in the ranking; story_members links it to the other
+	// sources' articles. Coverage adds a buzz component to the score.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE story_members (
+				date TEXT NOT NULL,
+				article_id INTEGER NOT NULL,
+				member_id INTEGER NOT NULL,
+				PRIMARY KEY (date, article_id, member_id),
+				FOREIGN KEY (article_id) REFERENCES articles(id),
+				FOREIGN KEY (member_id) REFERENCES articles(id)
+			)`,
+			`ALTER TABLE candidates ADD COLUMN buzz REAL NOT NULL DEFAULT 0`,
+			`ALTER TABLE candidates ADD COLUMN sources INTEGER NOT NULL DEFAULT 1`,
+		)
+	},
+
+	// 8: categories are configured in the sources file, so rankings no longer
+	// restricts them to domestic and global. SQLite cannot drop a CHECK
+	// constraint, so the table is rebuilt.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE rankings_new (
+				date TEXT NOT NULL,
+				category TEXT NOT NULL,
+				rank INTEGER NOT NULL DEFAULT 0,
+				score REAL NOT NULL DEFAULT 0,
+				article_id INTEGER NOT NULL,
+				status TEXT NOT NULL DEFAULT 'active',
+				updated_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+				PRIMARY KEY (date, article_id),
+				FOREIGN KEY (article_id) REFERENCES articles(id)
+			)`,
+			`INSERT INTO rankings_new (date, category, rank, score, article_id, status, updated_at)
+				SELECT date, category, rank, score, article_id, status, updated_at FROM rankings`,
+			`DROP TABLE rankings`,
+			`ALTER TABLE rankings_new RENAME TO rankings`,
+			`CREATE INDEX idx_rankings_date ON rankings(date, status, category, rank)`,
+		)
+	},
+
+	// 9: topic tags assigned to articles by the tag rules.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE article_tags (
+				article_id INTEGER NOT NULL,
+				tag TEXT NOT NULL,
+				PRIMARY KEY (article_id, tag),
+				FOREIGN KEY (article_id) REFERENCES articles(id)
+			)`,
+			`CREATE INDEX idx_article_tags_tag ON article_tags(tag, article_id)`,
+		)
+	},
+
+	// 10: canonical names of the entities articles mention, from the gazetteer.
+	func(tx *sql.Tx) error {
+		return execAll(tx,
+			`CREATE TABLE article_entities (
+				article_id INTEGER NOT NULL,
+				entity TEXT NOT NULL,
+				PRIMARY KEY (article_id, entity),
+				FOREIGN KEY (article_id) REFERENCES articles(id)
+			)`,
+			`CREATE INDEX idx_article_entities_entity ON article_entities(entity, article_id)`,
+		)
+	},
+
+	// 11: full-text search index over article titles and summaries, see
+	// search.go. It is filled from Go, which segments CJK text.
+	func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`CREATE VIRTUAL TABLE articles_fts USING fts5(
+				title, summary, tokenize = 'unicode61 remove_diacritics 2'
+			)`); err != nil {
+			return err
+		}
+		return indexArticles(tx)
+	},
+
+	// 12: the search index also holds the last character of every CJK run,
+	// which was unsearchable on its own.
+	func(tx *sql.Tx) error {
+		return indexArticles(tx)
+	},
+
+	// 13: the latest ranking date of an article, looked up for every row of
+	// the entity and search listings, no longer scans the whole table.
+	func(tx *sql.Tx) error {
+		_, err := tx.Exec(`CREATE INDEX idx_rankings_article ON rankings(article_id, status, date)`)
+		return err
+	},
+
+	// 14: candidates are only kept for the latest runs of each date; drop the
+	// pools stored before that limit existed.
+	func(tx *sql.Tx) error {
+		return pruneCandidates(tx, "")
+	},
+}
+
+func execAll(tx *sql.Tx, queries ...string) error {
+	for _, q := range queries {
+		if _, err := tx.Exec(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// canonicalizeNews fills canonical_url for rows written before it existed.
+func canonicalizeNews(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, source_url FROM news WHERE canonical_url = ''`)
+	if err != nil {
+		return err
+	}
+	urls := make(map[int64]string)
+	for rows.Next() {
+		var id int64
+		var u string
+		if err := rows.Scan(&id, &u); err != nil {
+			rows.Close()
+			return err
+		}
+		urls[id] = urlnorm.Canonical(u)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for id, u := range urls {
+		if _, err := tx.Exec(`UPDATE news SET canonical_url = ? WHERE id = ?`, u, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// upgrade applies the migrations newer than version, each in its own
+// transaction.
+func (db *DB) upgrade(version int) error {
+	for v := version; v < len(migrations); v++ {
+		tx, err := db.conn.Begin()
+		if err != nil {
+			return err
+		}
+		if err := migrations[v](tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", v+1, err)
+		}
+		if _, err := tx.Exec(fmt.Sprintf(`PRAGMA user_version = %d`, v+1)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
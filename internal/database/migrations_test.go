@@ -0,0 +1,106 @@
+package database
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateNews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "news.db")
+	conn, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The schema of user_version 0: one news row per ranking, so an article
+	// ranked again on another day has another row and another comment thread.
+	for _, q := range []string{
+		`CREATE TABLE news (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			title TEXT NOT NULL,
+			summary TEXT DEFAULT '',
+			source_url TEXT DEFAULT '',
+			source_name TEXT DEFAULT '',
+			category TEXT NOT NULL CHECK(category IN ('domestic', 'global')),
+			publish_date TEXT NOT NULL,
+			rank INTEGER DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE comments (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			news_id INTEGER NOT NULL,
+			author TEXT DEFAULT '匿名',
+			content TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+			FOREIGN KEY (news_id) REFERENCES news(id) ON DELETE CASCADE
+		)`,
+		`INSERT INTO news (id, title, source_url, category, publish_date, rank) VALUES
+			(1, 'A', 'https://www.a.example/story?utm_source=rss', 'global', '2026-01-01', 1),
+			(2, 'B', 'https://b.example/story', 'domestic', '2026-01-01', 1),
+			(3, 'A again', 'http://a.example/story/', 'global', '2026-01-02', 2),
+			(4, 'No link', '', 'domestic', '2026-01-02', 1)`,
+		`INSERT INTO comments (id, news_id, content) VALUES
+			(1, 1, 'on A'), (2, 3, 'on A again'), (3, 2, 'on B'), (4, 4, 'on no link')`,
+	} {
+		if _, err := conn.Exec(q); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	db, err := New(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	query := func(q string) string {
+		t.Helper()
+		rows, err := db.conn.Query(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		var got []string
+		for rows.Next() {
+			var a, b, c, d any
+			if err := rows.Scan(&a, &b, &c, &d); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, fmt.Sprintf("%v %v %v %v", a, b, c, d))
+		}
+		if err := rows.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(got, "\n")
+	}
+
+	// The first row of each URL keeps its ID as the article ID.
+	tests := []struct {
+		name, query, want string
+	}{
+		{"articles", `SELECT id, canonical_url, title, source_url FROM articles ORDER BY id`, strings.Join([]string{
+			"1 https://a.example/story A https://www.a.example/story?utm_source=rss",
+			"2 https://b.example/story B https://b.example/story",
+			"4 news:4 No link ",
+		}, "\n")},
+		{"rankings", `SELECT date, category, rank, article_id FROM rankings ORDER BY date, category`, strings.Join([]string{
+			"2026-01-01 domestic 1 2",
+			"2026-01-01 global 1 1",
+			"2026-01-02 domestic 1 4",
+			"2026-01-02 global 2 1",
+		}, "\n")},
+		{"comments", `SELECT id, news_id, content, author FROM comments ORDER BY id`, strings.Join([]string{
+			"1 1 on A 匿名",
+			"2 1 on A again 匿名",
+			"3 2 on B 匿名",
+			"4 4 on no link 匿名",
+		}, "\n")},
+	}
+	for _, tt := range tests {
+		if got := query(tt.query); got != tt.want {
+			t.Errorf("%s:\n%s\nwant:\n%s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"database/sql"
+	"strings"
+	"top-ai-news/internal/model"
+	"top-ai-news/internal/textutil"
+	"unicode"
+)
+
+// The search index articles_fts holds the title and summary of every article,
+// keyed by article ID, with each run of CJK text segmented into overlapping
+// bigrams followed by its last character: "大模型发布" is indexed as
+// "大模 模型 型发 发布 布". The unicode61 tokenizer then splits Latin text on
+// word boundaries as usual. A Chinese query matches wherever its own bigrams
+// occur in sequence, and a single character as the prefix of a bigram or as
+// the last character of a run.
+
+// searchRecencyDays is the age, in days, at which a match scores half of its
+// bm25 relevance; older matches keep decaying towards zero.
+const searchRecencyDays = 30
+
+// isWord reports whether r is part of a Latin-style word, which the tokenizer
+// keeps whole.
+func isWord(r rune) bool {
+	return (unicode.IsLetter(r) || unicode.IsNumber(r)) && !textutil.IsCJK(r)
+}
+
+// segment rewrites text for the search index: every run of CJK characters
+// becomes its space-separated bigrams and its last character, and the rest is
+// kept for the tokenizer.
+func segment(text string) string {
+	return segmentRuns(text, false)
+}
+
+// segmentRuns segments text like segment. If open is set, text may continue
+// past its end, as a query term does, so a final run of two or more
+// characters leaves out its last character.
+func segmentRuns(text string, open bool) string {
+	var b strings.Builder
+	var run []rune
+	flush := func(final bool) {
+		if len(run) == 0 {
+			return
+		}
+		b.WriteByte(' ')
+		for i := 0; i+1 < len(run); i++ {
+			b.WriteRune(run[i])
+			b.WriteRune(run[i+1])
+			b.WriteByte(' ')
+		}
+		if len(run) == 1 || !(final && open) {
+			b.WriteRune(run[len(run)-1])
+		}
+		b.WriteByte(' ')
+		run = run[:0]
+	}
+	for _, r := range text {
+		if textutil.IsCJK(r) {
+			run = append(run, r)
+			continue
+		}
+		flush(false)
+		b.WriteRune(r)
+	}
+	flush(true)
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// SearchTerms splits a search query into its terms: whitespace-separated
+// words, or phrases in double quotes. Terms without a letter or digit are
+// dropped.
+func SearchTerms(q string) []string {
+	var terms []string
+	for i, part := range strings.Split(q, `"`) {
+		words := []string{strings.TrimSpace(part)}
+		if i%2 == 0 { // outside quotes
+			words = strings.Fields(part)
+		}
+		for _, w := range words {
+			if strings.IndexFunc(w, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsNumber(r) }) >= 0 {
+				terms = append(terms, w)
+			}
+		}
+	}
+	return terms
+}
+
+// MatchedRunes marks the characters of text that are part of an occurrence of
+// one of terms, ignoring case. Occurrences must start and end on a token
+// boundary as the index sees it, so "ai" is found in "OpenAI 的 AI" only once
+// while a CJK term is found anywhere.
+func MatchedRunes(text []rune, terms []string) []bool {
+	lower := make([]rune, len(text))
+	for i, r := range text {
+		lower[i] = unicode.ToLower(r)
+	}
+	marked := make([]bool, len(text))
+	for _, t := range terms {
+		term := []rune(strings.ToLower(t))
+		for i := 0; i+len(term) <= len(lower); i++ {
+			j := i + len(term)
+			if string(lower[i:j]) != string(term) ||
+				isWord(term[0]) && i > 0 && isWord(lower[i-1]) ||
+				isWord(term[len(term)-1]) && j < len(lower) && isWord(lower[j]) {
+				continue
+			}
+			for k := i; k < j; k++ {
+				marked[k] = true
+			}
+		}
+	}
+	return marked
+}
+
+// matchExpr turns search terms into an FTS5 query that requires all of them.
+// Each term is a phrase of its segmented tokens. A term ending in a single CJK
+// character ends in a prefix query, which matches the character both as the
+// last character of a run and as the start of a bigram.
+func matchExpr(terms []string) string {
+	phrases := make([]string, len(terms))
+	for i, t := range terms {
+		phrases[i] = `"` + strings.ReplaceAll(segmentRuns(t, true), `"`, `""`) + `"`
+		if r := []rune(t); textutil.IsCJK(r[len(r)-1]) && (len(r) == 1 || !textutil.IsCJK(r[len(r)-2])) {
+			phrases[i] += "*"
+		}
+	}
+	return strings.Join(phrases, " AND ")
+}
+
+// indexArticle replaces the search index entry of an article.
+func indexArticle(tx *sql.Tx, id int64, title, summary string) error {
+	if _, err := tx.Exec(`DELETE FROM articles_fts WHERE rowid = ?`, id); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`INSERT INTO articles_fts (rowid, title, summary) VALUES (?, ?, ?)`,
+		id, segment(title), segment(summary))
+	return err
+}
+
+// indexArticles builds the search index of the articles stored before it
+// existed.
+func indexArticles(tx *sql.Tx) error {
+	rows, err := tx.Query(`SELECT id, title, summary FROM articles`)
+	if err != nil {
+		return err
+	}
+	type article struct {
+		id             int64
+		title, summary string
+	}
+	var articles []article
+	for rows.Next() {
+		var a article
+		if err := rows.Scan(&a.id, &a.title, &a.summary); err != nil {
+			rows.Close()
+			return err
+		}
+		articles = append(articles, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, a := range articles {
+		if err := indexArticle(tx, a.id, a.title, a.summary); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SearchOptions are the parameters of SearchNews. Empty filters match
+// everything.
+type SearchOptions struct {
+	Terms    []string // from SearchTerms; all must match
+	From, To string   // ranking dates, inclusive
+	Category string
+	Source   string
+	Limit    int
+	Offset   int
+}
+
+// SearchNews returns the ranked news matching opts, each at the latest date
+// in [From, To] it was ranked on, and the total number of them. Matches are
+// ordered by bm25 relevance, title matches counting double, discounted by the
+// age of the ranking date.
+func (db *DB) SearchNews(opts SearchOptions) ([]model.SearchHit, int, error) {
+	latest := `SELECT MAX(date) FROM rankings WHERE article_id = a.id AND status = 'active'`
+	where := `articles_fts MATCH ?`
+	latestArgs, args := []interface{}{}, []interface{}{matchExpr(opts.Terms)}
+	if opts.From != "" {
+		latest += ` AND date >= ?`
+		latestArgs = append(latestArgs, opts.From)
+	}
+	if opts.To != "" {
+		latest += ` AND date <= ?`
+		latestArgs = append(latestArgs, opts.To)
+	}
+	if opts.Category != "" {
+		where += ` AND r.category = ?`
+		args = append(args, opts.Category)
+	}
+	if opts.Source != "" {
+		where += ` AND a.source_name = ?`
+		args = append(args, opts.Source)
+	}
+	from := `FROM articles_fts
+		 JOIN articles a ON a.id = articles_fts.rowid
+		 JOIN rankings r ON r.article_id = a.id AND r.status = 'active' AND r.date = (` + latest + `)
+		 WHERE ` + where
+	args = append(latestArgs, args...)
+
+	var total int
+	if err := db.conn.QueryRow(`SELECT COUNT(*) `+from, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := db.conn.Query(
+		`SELECT a.id, a.title, a.summary, a.source_url, a.canonical_url, a.source_name, a.published_at,
+		        r.category, r.date, r.rank, r.score, r.status, a.created_at,
+		        -bm25(articles_fts, 2.0, 1.0) / (1 + MAX(julianday('now') - julianday(r.date), 0) / ?) AS relevance
+		 `+from+` ORDER BY relevance DESC, r.date DESC, a.id DESC LIMIT ? OFFSET ?`,
+		append(append([]interface{}{float64(searchRecencyDays)}, args...), opts.Limit, opts.Offset)...,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var news []model.News
+	var relevance []float64
+	for rows.Next() {
+		var n model.News
+		var published sql.NullTime
+		var r float64
+		if err := rows.Scan(&n.ID, &n.Title, &n.Summary, &n.SourceURL, &n.CanonicalURL, &n.SourceName, &published,
+			&n.Category, &n.PublishDate, &n.Rank, &n.Score, &n.Status, &n.CreatedAt, &r); err != nil {
+			return nil, 0, err
+		}
+		if published.Valid {
+			n.PublishedAt = published.Time
+		}
+		news = append(news, n)
+		relevance = append(relevance, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := db.attachCoverageByDate(news); err != nil {
+		return nil, 0, err
+	}
+	if err := db.attachLinks(news); err != nil {
+		return nil, 0, err
+	}
+	hits := make([]model.SearchHit, len(news))
+	for i := range news {
+		hits[i] = model.SearchHit{News: news[i], Relevance: relevance[i]}
+	}
+	return hits, total, nil
+}
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"top-ai-news/internal/model"
+)
+
+func TestSegment(t *testing.T) {
+	tests := []struct {
+		text, index, query string
+	}{
+		{"大模型发布", "大模 模型 型发 发布 布", "大模 模型 型发 发布"},
+		{"型", "型", "型"},
+		{"OpenAI 发布V3大模型", "OpenAI 发布 布 V3 大模 模型 型", "OpenAI 发布 布 V3 大模 模型"},
+		{"大模型V3", "大模 模型 型 V3", "大模 模型 型 V3"},
+		{"GPT-5 launch", "GPT-5 launch", "GPT-5 launch"},
+	}
+	for _, tt := range tests {
+		if got := segment(tt.text); got != tt.index {
+			t.Errorf("segment(%q) = %q, want %q", tt.text, got, tt.index)
+		}
+		if got := segmentRuns(tt.text, true); got != tt.query {
+			t.Errorf("segmentRuns(%q, true) = %q, want %q", tt.text, got, tt.query)
+		}
+	}
+}
+
+func TestSearchTerms(t *testing.T) {
+	tests := []struct {
+		q    string
+		want []string
+	}{
+		{"DeepSeek 开源", []string{"DeepSeek", "开源"}},
+		{`"open source" llm`, []string{"open source", "llm"}},
+		{`  !!! - 融资 `, []string{"融资"}},
+		{`"unclosed phrase`, []string{"unclosed phrase"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := SearchTerms(tt.q); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SearchTerms(%q) = %q, want %q", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestMatchExpr(t *testing.T) {
+	tests := []struct {
+		terms []string
+		want  string
+	}{
+		{[]string{"大模型", "openai"}, `"大模 模型" AND "openai"`},
+		{[]string{"型"}, `"型"*`},
+		{[]string{"V3大"}, `"V3 大"*`},
+		{[]string{`say "hi"`}, `"say ""hi"""`},
+	}
+	for _, tt := range tests {
+		if got := matchExpr(tt.terms); got != tt.want {
+			t.Errorf("matchExpr(%q) = %s, want %s", tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestMatchedRunes(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string // the marked characters
+	}{
+		{"OpenAI said AI is here", []string{"ai"}, "AI"},
+		{"发布V3大模型，新模型", []string{"型"}, "型型"},
+		{"GPT大模型", []string{"gpt", "模型"}, "GPT模型"},
+		{"Open-source models", []string{"open"}, "Open"},
+	}
+	for _, tt := range tests {
+		text := []rune(tt.text)
+		var got []rune
+		for i, m := range MatchedRunes(text, tt.terms) {
+			if m {
+				got = append(got, text[i])
+			}
+		}
+		if string(got) != tt.want {
+			t.Errorf("MatchedRunes(%q, %q) marked %q, want %q", tt.text, tt.terms, string(got), tt.want)
+		}
+	}
+}
+
+func TestSearchNews(t *testing.T) {
+	db, err := New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	runID, err := db.CreateFetchRun("2026-02-01", "cli", "{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands := []model.Candidate{
+		{News: model.News{Title: "DeepSeek 发布V3大模型", Summary: "性能对标新模型，", CanonicalURL: "https://a.example/1",
+			Category: "domestic", PublishDate: "2026-02-01", Rank: 1}},
+		{News: model.News{Title: "OpenAI said to raise funds", CanonicalURL: "https://b.example/2",
+			Category: "global", PublishDate: "2026-02-01", Rank: 1}},
+	}
+	if _, err := db.SaveRanking(runID, "2026-02-01", cands, false); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		q     string
+		total int
+	}{
+		{"型", 1},
+		{"大模型", 1},
+		{"模型 deepseek", 1},
+		{"v3", 1},
+		{"ai", 0}, // not a token of "OpenAI" or "said"
+		{"openai", 1},
+		{"融资", 0},
+	}
+	for _, tt := range tests {
+		_, total, err := db.SearchNews(SearchOptions{Terms: SearchTerms(tt.q), Limit: 20})
+		if err != nil {
+			t.Fatalf("%q: %v", tt.q, err)
+		}
+		if total != tt.total {
+			t.Errorf("%q: %d hits, want %d", tt.q, total, tt.total)
+		}
+	}
+}
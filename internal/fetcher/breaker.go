@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"time"
+	"top-ai-news/internal/model"
+
+	"github.com/mmcdole/gofeed"
+)
+
+// Circuit breaker states as reported by the admin API.
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half_open" // cooldown elapsed, the next run probes the feed
+)
+
+// BreakerStatus reports the state of a feed's breaker at the given time.
+func BreakerStatus(b *model.FeedBreaker, opts FetchOptions, now time.Time) string {
+	if b == nil || b.OpenedAt == nil {
+		return BreakerClosed
+	}
+	if now.Sub(*b.OpenedAt) >= opts.BreakerCooldown {
+		return BreakerHalfOpen
+	}
+	return BreakerOpen
+}
+
+// fetchWithRetry calls FetchFeed and retries transient failures with jittered
+// exponential backoff, as long as the delay still fits before ctx's deadline.
+func fetchWithRetry(ctx context.Context, src FeedSource, cache *model.FeedCache, kw Keywords, opts FetchOptions) (FeedResult, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := FetchFeed(ctx, src, cache, kw)
+		if err == nil || attempt >= opts.Retries || !isTransient(err) {
+			return res, err
+		}
+
+		delay := backoffDelay(attempt, opts)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return res, err
+		}
+		log.Printf("↻ 抓取 %s 失败，%v 后重试 (%d/%d): %v", src.Name, delay.Round(time.Millisecond), attempt+1, opts.Retries, err)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		}
+	}
+}
+
+// backoffDelay returns base·2^attempt capped at max, jittered to [d/2, d).
+func backoffDelay(attempt int, opts FetchOptions) time.Duration {
+	d := opts.BackoffBase << attempt
+	if d <= 0 || (opts.BackoffMax > 0 && d > opts.BackoffMax) {
+		d = opts.BackoffMax
+	}
+	if d <= 0 {
+		return 0
+	}
+	half := d / 2
+	return half + time.Duration(rand.Int64N(int64(d-half)))
+}
+
+// isTransient reports whether a fetch error is worth retrying: network errors,
+// 5xx responses and 429. Parse errors and other 4xx responses are not.
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var httpErr gofeed.HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// recordOutcome updates a feed's breaker after a run. A success closes the
+// breaker; a failure counts towards the threshold, and a failed probe re-opens
+// it for another cooldown.
+func (f *Fetcher) recordOutcome(src FeedSource, prev *model.FeedBreaker, fetchErr error, opts FetchOptions) {
+	if fetchErr == nil && (prev == nil || prev.Failures == 0) {
+		return
+	}
+
+	b := model.FeedBreaker{FeedURL: src.URL, SourceName: src.Name}
+	if fetchErr == nil {
+		if prev.OpenedAt != nil {
+			log.Printf("✓ %s 恢复正常，熔断器关闭", src.Name)
+		}
+	} else {
+		if prev != nil {
+			b.Failures = prev.Failures
+			b.OpenedAt = prev.OpenedAt
+		}
+		b.Failures++
+		b.LastError = fetchErr.Error()
+		if opts.BreakerThreshold > 0 && b.Failures >= opts.BreakerThreshold {
+			now := time.Now()
+			b.OpenedAt = &now
+			log.Printf("⛔ %s 已连续失败 %d 次，熔断 %v", src.Name, b.Failures, opts.BreakerCooldown)
+		}
+	}
+
+	if err := f.db.SaveFeedBreaker(b); err != nil {
+		log.Printf("保存 %s 熔断状态失败: %v", src.Name, err)
+	}
+}
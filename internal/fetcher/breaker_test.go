@@ -0,0 +1,132 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	opts := FetchOptions{BackoffBase: time.Second, BackoffMax: 8 * time.Second}
+	tests := []struct {
+		attempt int
+		opts    FetchOptions
+		want    time.Duration // the delay before jitter
+	}{
+		{0, opts, time.Second},
+		{1, opts, 2 * time.Second},
+		{2, opts, 4 * time.Second},
+		{3, opts, 8 * time.Second},
+		{4, opts, 8 * time.Second},                                    // capped
+		{70, opts, 8 * time.Second},                                   // the shift overflows
+		{5, FetchOptions{BackoffBase: time.Second}, 32 * time.Second}, // no cap
+		{2, FetchOptions{}, 0},
+	}
+	for _, tt := range tests {
+		for range 50 {
+			d := backoffDelay(tt.attempt, tt.opts)
+			if tt.want == 0 && d != 0 || tt.want > 0 && (d < tt.want/2 || d >= tt.want) {
+				t.Errorf("attempt %d with %+v: delay %v, want in [%v, %v)", tt.attempt, tt.opts, d, tt.want/2, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{gofeed.HTTPError{StatusCode: 500}, true},
+		{gofeed.HTTPError{StatusCode: 503}, true},
+		{gofeed.HTTPError{StatusCode: 429}, true},
+		{fmt.Errorf("fetch: %w", gofeed.HTTPError{StatusCode: 502}), true},
+		{gofeed.HTTPError{StatusCode: 404}, false},
+		{gofeed.HTTPError{StatusCode: 403}, false},
+		{gofeed.HTTPError{StatusCode: 410}, false},
+		{&net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, true},
+		{&net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, true},
+		// The run's own deadline or cancellation ends the retries.
+		{context.DeadlineExceeded, false},
+		{fmt.Errorf("fetch: %w", context.Canceled), false},
+		{errors.New("parse feed: unexpected EOF"), false},
+	}
+	for _, tt := range tests {
+		if got := isTransient(tt.err); got != tt.want {
+			t.Errorf("isTransient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	f := newTestFetcher(t, time.UTC)
+	src := FeedSource{Name: "flaky", URL: "https://flaky.example/rss"}
+	opts := FetchOptions{BreakerThreshold: 3, BreakerCooldown: time.Hour}
+	fail := gofeed.HTTPError{StatusCode: 503, Status: "503 Service Unavailable"}
+
+	run := func(err error) (string, int) {
+		t.Helper()
+		prev, dbErr := f.db.GetFeedBreaker(src.URL)
+		if dbErr != nil {
+			t.Fatal(dbErr)
+		}
+		f.recordOutcome(src, prev, err, opts)
+		b, dbErr := f.db.GetFeedBreaker(src.URL)
+		if dbErr != nil {
+			t.Fatal(dbErr)
+		}
+		if b == nil {
+			return BreakerStatus(nil, opts, time.Now()), 0
+		}
+		return BreakerStatus(b, opts, time.Now()), b.Failures
+	}
+
+	// A healthy feed leaves no state behind.
+	if status, n := run(nil); status != BreakerClosed || n != 0 {
+		t.Fatalf("after a success: %s with %d failures", status, n)
+	}
+	for i := 1; i < opts.BreakerThreshold; i++ {
+		if status, n := run(fail); status != BreakerClosed || n != i {
+			t.Fatalf("after %d failures: %s with %d failures, want closed", i, status, n)
+		}
+	}
+	if status, n := run(fail); status != BreakerOpen || n != opts.BreakerThreshold {
+		t.Fatalf("after %d failures: %s, want open", n, status)
+	}
+
+	// The breaker stays open for the cooldown, then lets a probe through.
+	b, err := f.db.GetFeedBreaker(src.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opened := *b.OpenedAt
+	if got := BreakerStatus(b, opts, opened.Add(opts.BreakerCooldown-time.Second)); got != BreakerOpen {
+		t.Errorf("within the cooldown: %s, want open", got)
+	}
+	if got := BreakerStatus(b, opts, opened.Add(opts.BreakerCooldown)); got != BreakerHalfOpen {
+		t.Errorf("after the cooldown: %s, want half_open", got)
+	}
+
+	// A failed probe opens it for another cooldown; a success closes it.
+	if status, n := run(fail); status != BreakerOpen || n != opts.BreakerThreshold+1 {
+		t.Errorf("after a failed probe: %s with %d failures, want open", status, n)
+	}
+	if status, n := run(nil); status != BreakerClosed || n != 0 {
+		t.Errorf("after a successful probe: %s with %d failures, want closed", status, n)
+	}
+
+	// A threshold of 0 never opens the breaker.
+	opts.BreakerThreshold = 0
+	for range 5 {
+		run(fail)
+	}
+	if status, n := run(fail); status != BreakerClosed || n != 6 {
+		t.Errorf("with breaking disabled: %s with %d failures, want closed", status, n)
+	}
+}
@@ -0,0 +1,116 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultTopN is the number of articles of a category that make a day's
+// ranking when the category does not set top_n.
+const DefaultTopN = 5
+
+// maxTopN bounds top_n; larger rankings are not a daily digest any more.
+const maxTopN = 50
+
+// Category is a section of the daily ranking. Every feed belongs to one.
+type Category struct {
+	Name  string `yaml:"name" json:"name"`                     // identifier used by sources, keywords and the API
+	Label string `yaml:"label,omitempty" json:"label"`         // section title, defaults to Name
+	Icon  string `yaml:"icon,omitempty" json:"icon,omitempty"` // shown before the label
+	TopN  int    `yaml:"top_n,omitempty" json:"top_n"`         // articles in the day's ranking, defaults to DefaultTopN
+}
+
+// UnmarshalYAML accepts a bare name and fills unspecified fields with their
+// defaults.
+func (c *Category) UnmarshalYAML(value *yaml.Node) error {
+	type plain Category
+	p := plain{TopN: DefaultTopN}
+	if value.Kind == yaml.ScalarNode {
+		p.Name = value.Value
+	} else if err := decodeStrict(value, &p); err != nil {
+		return err
+	}
+	if p.Label == "" {
+		p.Label = p.Name
+	}
+	*c = Category(p)
+	return nil
+}
+
+// Categories lists the sections of the daily ranking in display order.
+type Categories []Category
+
+// DefaultCategories returns the categories used when the sources file has no
+// categories section.
+func DefaultCategories() Categories {
+	return Categories{
+		{Name: "domestic", Label: "国内 AI 热点", Icon: "🇨🇳", TopN: DefaultTopN},
+		{Name: "global", Label: "全球 AI 热点", Icon: "🌍", TopN: DefaultTopN},
+	}
+}
+
+// Get returns the category called name.
+func (c Categories) Get(name string) (Category, bool) {
+	for _, cat := range c {
+		if cat.Name == name {
+			return cat, true
+		}
+	}
+	return Category{}, false
+}
+
+// Has reports whether name is a configured category.
+func (c Categories) Has(name string) bool {
+	_, ok := c.Get(name)
+	return ok
+}
+
+// Names returns the category names in display order.
+func (c Categories) Names() []string {
+	names := make([]string, len(c))
+	for i, cat := range c {
+		names[i] = cat.Name
+	}
+	return names
+}
+
+// identifier restricts category and tag names to what is safe in URLs and
+// CSS class names.
+var identifier = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+func (c Categories) validate() error {
+	if len(c) == 0 {
+		return errors.New("categories: at least one category is required")
+	}
+	var errs []error
+	seen := make(map[string]int)
+	for i, cat := range c {
+		where := fmt.Sprintf("categories[%d]", i)
+		if !identifier.MatchString(cat.Name) {
+			errs = append(errs, fmt.Errorf("%s: name %q must be lower-case letters, digits, '-' or '_'", where, cat.Name))
+			continue
+		}
+		where = fmt.Sprintf("%s %q", where, cat.Name)
+		if j, dup := seen[cat.Name]; dup {
+			errs = append(errs, fmt.Errorf("%s: duplicate name (already defined at categories[%d])", where, j))
+		} else {
+			seen[cat.Name] = i
+		}
+		if cat.TopN < 1 || cat.TopN > maxTopN {
+			errs = append(errs, fmt.Errorf("%s: top_n %d out of range [1, %d]", where, cat.TopN, maxTopN))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// unknownCategory reports a reference to a category that is not configured.
+func (c Categories) unknownCategory(where, name string) error {
+	if c.Has(name) {
+		return nil
+	}
+	return fmt.Errorf("%s: unknown category %q (want one of %s)", where, name, strings.Join(c.Names(), ", "))
+}
@@ -0,0 +1,72 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCategoriesConfig(t *testing.T) {
+	set := mustParseSources(t, `
+categories:
+  - domestic
+  - {name: research, label: 研究, top_n: 3}
+ranking:
+  categories:
+    research:
+      half_life_hours: 48
+keywords:
+  research:
+    exclude: [webinar]
+sources:
+  - {name: a, url: "https://example.com/feed", category: domestic}
+  - {name: b, url: "https://arxiv.example.org/feed", category: research}
+`)
+
+	if got := set.Categories.Names(); strings.Join(got, ",") != "domestic,research" {
+		t.Errorf("categories = %v, want [domestic research]", got)
+	}
+	if c, _ := set.Categories.Get("domestic"); c.Label != "domestic" || c.TopN != DefaultTopN {
+		t.Errorf("bare category = %+v, want label domestic and top_n %d", c, DefaultTopN)
+	}
+	if c, _ := set.Categories.Get("research"); c.Label != "研究" || c.TopN != 3 {
+		t.Errorf("research = %+v, want label 研究 and top_n 3", c)
+	}
+	if set.Ranking.Params("research").HalfLifeHours != 48 {
+		t.Error("ranking parameters of a new category were not applied")
+	}
+
+	// A new category starts from both built-in filter dictionaries.
+	research := set.Keywords.For("research")
+	for _, h := range []string{"大模型推理加速新方法", "A new benchmark for LLM agents"} {
+		if !research.Admit(h, false) {
+			t.Errorf("%q: not admitted by the default research filter", h)
+		}
+	}
+	if research.Admit("Join our LLM webinar", true) {
+		t.Error("configured exclude dictionary was not applied")
+	}
+	if _, ok := set.Keywords["global"]; ok {
+		t.Error("dictionaries kept for an unconfigured category")
+	}
+}
+
+func TestCategoriesConfigErrors(t *testing.T) {
+	wantSourcesErrors(t, `
+categories:
+  - {name: research, top_n: 0}
+  - research
+  - Open Source
+keywords:
+  global:
+    exclude: [spam]`+oneSource,
+		"top_n 0 out of range",
+		`categories[1] "research": duplicate name`,
+		`name "Open Source" must be`,
+		"keywords.global: unknown category",
+		`unknown category "global"`)
+
+	wantSourcesErrors(t, `
+categories:
+  - {name: global, top-n: 3}`+oneSource,
+		`line 3: unknown field "top-n" (want one of icon, label, name, top_n)`)
+}
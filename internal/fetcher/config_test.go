@@ -0,0 +1,97 @@
+package fetcher
+
+import (
+	"strings"
+	"testing"
+)
+
+// oneSource is a sources section for documents that test other sections.
+const oneSource = `
+sources:
+  - {name: a, url: "https://example.com/feed", category: global}
+`
+
+// mustParseSources parses a sources document that must be valid.
+func mustParseSources(t *testing.T, doc string) *SourceSet {
+	t.Helper()
+	set, err := ParseSources([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return set
+}
+
+// wantSourcesErrors parses a sources document that must be invalid and
+// checks that the error mentions every one of want.
+func wantSourcesErrors(t *testing.T, doc string, want ...string) {
+	t.Helper()
+	_, err := ParseSources([]byte(doc))
+	if err == nil {
+		t.Fatal("want an error")
+	}
+	for _, w := range want {
+		if !strings.Contains(err.Error(), w) {
+			t.Errorf("error %q does not mention %q", err, w)
+		}
+	}
+}
+
+func TestFetchConfigUnknownFields(t *testing.T) {
+	wantSourcesErrors(t, `
+fetch:
+  retries: 2
+  retires: 2`+oneSource,
+		`line 4: unknown field "retires"`)
+}
+
+func TestValidateSources(t *testing.T) {
+	weight := func(w float64) *float64 { return &w }
+	off := false
+	ok := SourceEntry{Name: "a", URL: "https://example.com/feed", Category: "global"}
+	with := func(e SourceEntry) []SourceEntry { return []SourceEntry{ok, e} }
+	tests := []struct {
+		entries []SourceEntry
+		want    string // "" means valid
+	}{
+		{with(SourceEntry{Name: "b", URL: "http://b.example/rss", Category: "domestic", Weight: weight(1)}), ""},
+		{with(SourceEntry{Name: " a ", URL: "https://b.example/rss", Category: "global"}),
+			`sources[1] "a": duplicate name (already defined at sources[0])`},
+		{with(SourceEntry{URL: "https://b.example/rss", Category: "global"}),
+			`sources[1]: name is required`},
+		{with(SourceEntry{Name: "b", Category: "global"}),
+			`sources[1] "b": url is required`},
+		{with(SourceEntry{Name: "b", URL: "ftp://b.example/rss", Category: "global"}),
+			`sources[1] "b": bad url "ftp://b.example/rss": scheme must be http or https`},
+		{with(SourceEntry{Name: "b", URL: "b.example/rss", Category: "global"}),
+			`sources[1] "b": bad url "b.example/rss": scheme must be http or https`},
+		{with(SourceEntry{Name: "b", URL: "https:///rss", Category: "global"}),
+			`sources[1] "b": bad url "https:///rss": missing host`},
+		{with(SourceEntry{Name: "b", URL: "https://b.example/%zz", Category: "global"}),
+			`sources[1] "b": bad url "https://b.example/%zz": parse "https://b.example/%zz": invalid URL escape "%zz"`},
+		{with(SourceEntry{Name: "b", URL: "https://b.example/rss", Category: "research"}),
+			`sources[1] "b": unknown category "research" (want one of domestic, global)`},
+		{with(SourceEntry{Name: "b", URL: "https://b.example/rss", Category: "global", Weight: weight(1.5)}),
+			`sources[1] "b": weight 1.50 out of range [0, 1]`},
+		{[]SourceEntry{{Name: "a", URL: "https://example.com/feed", Category: "global", Enabled: &off}},
+			`no enabled sources`},
+		{nil, `no enabled sources`},
+	}
+	for _, tt := range tests {
+		err := ValidateSources(tt.entries, DefaultCategories())
+		switch {
+		case tt.want == "" && err != nil:
+			t.Errorf("%+v: %v", tt.entries, err)
+		case tt.want != "" && (err == nil || err.Error() != tt.want):
+			t.Errorf("%+v: error %v, want %q", tt.entries, err, tt.want)
+		}
+	}
+
+	// Every problem is reported at once.
+	err := ValidateSources([]SourceEntry{ok, {Name: "a", URL: "gopher://x", Category: "x"}}, DefaultCategories())
+	want := `sources[1] "a": duplicate name (already defined at sources[0])
+sources[1] "a": bad url "gopher://x": scheme must be http or https
+sources[1] "a": unknown category "x" (want one of domestic, global)`
+	if err == nil || err.Error() != want {
+		t.Errorf("error %v, want\n%s", err, want)
+	}
+}
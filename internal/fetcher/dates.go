@@ -0,0 +1,65 @@
+package fetcher
+
+import (
+	"fmt"
+	"time"
+)
+
+// maxBackfillDays bounds a single backfill request.
+const maxBackfillDays = 31
+
+// DateRange expands an inclusive yyyy-MM-dd range into its dates.
+func DateRange(from, to string) ([]string, error) {
+	start, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q", from)
+	}
+	end, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q", to)
+	}
+	if end.Before(start) {
+		return nil, fmt.Errorf("from %s is after to %s", from, to)
+	}
+
+	var dates []string
+	for d := start; !d.After(end); d = d.AddDate(0, 0, 1) {
+		if len(dates) == maxBackfillDays {
+			return nil, fmt.Errorf("range %s..%s exceeds %d days", from, to, maxBackfillDays)
+		}
+		dates = append(dates, d.Format("2006-01-02"))
+	}
+	return dates, nil
+}
+
+// Location returns the edition timezone.
+func (f *Fetcher) Location() *time.Location {
+	return f.loc
+}
+
+// Today returns the current edition date (yyyy-MM-dd) in the edition timezone.
+func (f *Fetcher) Today() string {
+	return time.Now().In(f.loc).Format("2006-01-02")
+}
+
+// dayBounds returns the [start, end) interval of date in the fetcher's timezone.
+func (f *Fetcher) dayBounds(date string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01-02", date, f.loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid date %q", date)
+	}
+	return start, start.AddDate(0, 0, 1), nil
+}
+
+// articlesBetween returns a copy of the articles published in [start, end).
+// A zero start keeps every article.
+func articlesBetween(articles []RawArticle, start, end time.Time) []RawArticle {
+	var out []RawArticle
+	for _, a := range articles {
+		if !start.IsZero() && (a.PublishDate.Before(start) || !a.PublishDate.Before(end)) {
+			continue
+		}
+		out = append(out, a)
+	}
+	return out
+}
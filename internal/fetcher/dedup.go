@@ -0,0 +1,202 @@
+package fetcher
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+	"strings"
+	"top-ai-news/internal/textutil"
+	"unicode"
+)
+
+// DedupOptions tunes near-duplicate detection.
+type DedupOptions struct {
+	// Threshold is the estimated Jaccard similarity of two articles' shingles
+	// at or above which they count as the same story; 0 disables near-duplicate
+	// detection, leaving only the exact title match.
+	Threshold float64 `yaml:"threshold" json:"threshold"`
+}
+
+// DefaultDedupOptions returns the options used when the sources file does
+// not set them.
+func DefaultDedupOptions() DedupOptions {
+	return DedupOptions{Threshold: 0.2}
+}
+
+func (o DedupOptions) validate() error {
+	if o.Threshold < 0 || o.Threshold > 1 {
+		return fmt.Errorf("ranking.dedup.threshold %.2f out of range [0, 1]", o.Threshold)
+	}
+	return nil
+}
+
+// numHashes is the length of a MinHash signature; the similarity estimate
+// has a standard error of about 1/sqrt(numHashes).
+const numHashes = 128
+
+// minShared is the number of shingles two articles must have in common to be
+// near-duplicates, so two short headlines are not matched on a single phrase.
+const minShared = 3
+
+// signature is the MinHash signature of an article's shingles, together with
+// the sorted hashes of the shingles themselves.
+type signature struct {
+	mins   [numHashes]uint64
+	hashes []uint64
+}
+
+// minHash computes the signature of the shingles of text. A text without
+// shingles gets a nil signature, which is similar to nothing.
+func minHash(text string) *signature {
+	shingles := shingle(text)
+	if len(shingles) == 0 {
+		return nil
+	}
+	var sig signature
+	for i := range sig.mins {
+		sig.mins[i] = math.MaxUint64
+	}
+	for s := range shingles {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		x := h.Sum64()
+		sig.hashes = append(sig.hashes, x)
+		for i := range sig.mins {
+			if v := mix(x ^ seeds[i]); v < sig.mins[i] {
+				sig.mins[i] = v
+			}
+		}
+	}
+	slices.Sort(sig.hashes)
+	return &sig
+}
+
+// similarity estimates the Jaccard similarity of the shingle sets behind a and b.
+func similarity(a, b *signature) float64 {
+	if a == nil || b == nil {
+		return 0
+	}
+	same := 0
+	for i := range a.mins {
+		if a.mins[i] == b.mins[i] {
+			same++
+		}
+	}
+	return float64(same) / numHashes
+}
+
+// shared counts the shingles a and b have in common.
+func shared(a, b *signature) int {
+	n := 0
+	for i, j := 0, 0; i < len(a.hashes) && j < len(b.hashes); {
+		switch {
+		case a.hashes[i] < b.hashes[j]:
+			i++
+		case a.hashes[i] > b.hashes[j]:
+			j++
+		default:
+			n++
+			i++
+			j++
+		}
+	}
+	return n
+}
+
+// nearDuplicate reports whether a and b are similar enough to be the same
+// story: an estimated Jaccard similarity of at least threshold, and at least
+// minShared shingles in common.
+func nearDuplicate(a, b *signature, threshold float64) bool {
+	if threshold <= 0 || similarity(a, b) < threshold {
+		return false
+	}
+	return shared(a, b) >= minShared
+}
+
+// seeds derive the numHashes hash functions from one.
+var seeds = func() (s [numHashes]uint64) {
+	for i := range s {
+		s[i] = mix(uint64(i) + 1)
+	}
+	return s
+}()
+
+// mix is the splitmix64 finaliser.
+func mix(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
+
+// stopWords are frequent English words that say nothing about the story.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"by": true, "for": true, "from": true, "has": true, "in": true, "is": true,
+	"it": true, "its": true, "of": true, "on": true, "that": true, "the": true,
+	"this": true, "to": true, "with": true, "new": true,
+}
+
+// shingle splits text into the features compared by minHash: pairs of
+// consecutive lower-cased words for scripts that separate words with spaces,
+// and overlapping character pairs for CJK text, which has no spaces to split
+// on. Single words such as "OpenAI" or "model" are shared by unrelated
+// headlines, so a word only counts on its own when it has no neighbour, like
+// a Latin name inside Chinese text.
+func shingle(text string) map[string]bool {
+	out := make(map[string]bool)
+	var word []rune
+	var words []string
+	var cjk []rune
+	flushWord := func() {
+		if len(word) > 0 {
+			if w := string(word); !stopWords[w] {
+				words = append(words, w)
+			}
+			word = word[:0]
+		}
+	}
+	flushWords := func() {
+		flushWord()
+		switch len(words) {
+		case 0:
+		case 1:
+			out[words[0]] = true
+		default:
+			for i := 0; i+1 < len(words); i++ {
+				out[words[i]+" "+words[i+1]] = true
+			}
+		}
+		words = words[:0]
+	}
+	flushCJK := func() {
+		switch len(cjk) {
+		case 0:
+		case 1:
+			out[string(cjk)] = true
+		default:
+			for i := 0; i+1 < len(cjk); i++ {
+				out[string(cjk[i:i+2])] = true
+			}
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case textutil.IsCJK(r):
+			flushWords()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, r)
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWords()
+	flushCJK()
+	return out
+}
@@ -0,0 +1,154 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDedupNearDuplicates(t *testing.T) {
+	articles := []RawArticle{
+		{SourceName: "techcrunch.com", Title: "OpenAI launches GPT-4o, a faster model that's free for all ChatGPT users",
+			Summary: "OpenAI announced a new flagship generative AI model on Monday that it calls GPT-4o. The model is faster and will be free for all ChatGPT users."},
+		{SourceName: "google.com", Title: "Google announces Gemini 1.5 Flash, a lighter model, at I/O",
+			Summary: "Google announced Gemini 1.5 Flash, a smaller model optimized for speed, at its I/O developer conference on Tuesday."},
+		{SourceName: "theverge.com", Title: "OpenAI releases GPT-4o, a faster model that's free for all ChatGPT users",
+			Summary: "OpenAI is launching GPT-4o, an iteration of the GPT-4 model that powers its hallmark product, ChatGPT. The updated model is much faster."},
+		{SourceName: "artificialintelligence-news.com", Title: "OpenAI unveils GPT-4o with real-time voice and vision",
+			Summary: "OpenAI has unveiled GPT-4o, its newest flagship model, which reasons across voice, text and vision and will be free for ChatGPT users."},
+		{SourceName: "jiqizhixin.com", Title: "DeepSeek发布V3大模型，性能比肩GPT-4o",
+			Summary: "深度求索今日发布DeepSeek-V3，在多项评测中性能比肩GPT-4o，训练成本大幅降低。"},
+		{SourceName: "36kr.com", Title: "DeepSeek-V3发布：训练成本仅557万美元，性能对标GPT-4o",
+			Summary: "DeepSeek发布新一代大模型V3，性能对标GPT-4o和Claude 3.5 Sonnet。"},
+		{SourceName: "infoq.cn", Title: "阿里通义千问发布Qwen2.5系列开源模型",
+			Summary: "阿里云发布通义千问Qwen2.5系列，开源多个尺寸的模型。"},
+		// Short headlines that only share "AI" are different stories.
+		{SourceName: "a.com", Title: "Startup uses AI"},
+		{SourceName: "b.com", Title: "Robot AI demo"},
+		{SourceName: "c.com", Title: "AI weekly roundup"},
+	}
+
+	var got []string
+	also := make(map[string][]string)
+	for _, a := range dedup(articles, DefaultDedupOptions()) {
+		got = append(got, a.SourceName)
+		for _, o := range a.Also {
+			also[a.SourceName] = append(also[a.SourceName], o.SourceName)
+		}
+	}
+	// The artificialintelligence-news.com rewrite shares little wording with
+	// the others beyond the names, so it stays a story of its own.
+	want := []string{"techcrunch.com", "google.com", "artificialintelligence-news.com", "jiqizhixin.com", "infoq.cn", "a.com", "b.com", "c.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("kept %v, want %v", got, want)
+	}
+	wantAlso := map[string][]string{
+		"techcrunch.com": {"theverge.com"},
+		"jiqizhixin.com": {"36kr.com"},
+	}
+	if !reflect.DeepEqual(also, wantAlso) {
+		t.Errorf("also covered by %v, want %v", also, wantAlso)
+	}
+
+	if n := len(dedup(articles, DedupOptions{})); n != len(articles) {
+		t.Errorf("threshold 0 kept %d articles, want all %d", n, len(articles))
+	}
+}
+
+// TestDedupUnrelatedHeadlines checks the default threshold against different
+// stories from the same company that share its name and words like "launches"
+// and "model".
+func TestDedupUnrelatedHeadlines(t *testing.T) {
+	pairs := [][2]RawArticle{
+		{{SourceName: "a.com", Title: "OpenAI launches o3-mini, its latest reasoning model",
+			Summary: "OpenAI launched o3-mini, a new cost-efficient reasoning model, in ChatGPT and the API on Friday."},
+			{SourceName: "b.com", Title: "OpenAI launches Sora, its video generation model, to ChatGPT Plus users",
+				Summary: "OpenAI is launching Sora, a model that generates videos from text prompts, for ChatGPT Plus and Pro subscribers."}},
+		{{SourceName: "a.com", Title: "Google launches Gemini 2.0 Flash model for developers",
+			Summary: "Google launched Gemini 2.0 Flash, a faster model, in the Gemini API and AI Studio."},
+			{SourceName: "b.com", Title: "Google launches Veo 2 video model to rival OpenAI's Sora",
+				Summary: "Google launched Veo 2, a video generation model that it says beats Sora, in VideoFX."}},
+		{{SourceName: "a.com", Title: "Meta launches Llama 4 Scout and Maverick models",
+			Summary: "Meta released Llama 4 Scout and Llama 4 Maverick, its first open-weight natively multimodal models."},
+			{SourceName: "b.com", Title: "Meta launches standalone Meta AI app to take on ChatGPT",
+				Summary: "Meta launched a standalone Meta AI app powered by its Llama 4 model, taking on ChatGPT."}},
+		{{SourceName: "a.com", Title: "Anthropic launches Claude 3.7 Sonnet, its first hybrid reasoning model",
+			Summary: "Anthropic launched Claude 3.7 Sonnet, a model that can answer quickly or think step by step."},
+			{SourceName: "b.com", Title: "Anthropic launches Claude Code, an agentic coding tool",
+				Summary: "Anthropic launched Claude Code, a command line tool that lets developers delegate coding tasks to Claude."}},
+		{{SourceName: "a.com", Title: "OpenAI launches GPT-4o, a faster model that's free for all ChatGPT users",
+			Summary: "OpenAI announced a new flagship generative AI model on Monday that it calls GPT-4o. The model is faster and will be free for all ChatGPT users."},
+			{SourceName: "b.com", Title: "OpenAI launches o3-mini, its latest reasoning model",
+				Summary: "OpenAI launched o3-mini, a new cost-efficient reasoning model, in ChatGPT and the API on Friday."}},
+	}
+	// Feeds without summaries leave only the headlines to compare.
+	for _, titles := range [][2]string{
+		{"OpenAI launches o3-mini reasoning model", "OpenAI launches Sora video model"},
+		{"OpenAI launches new model for coding", "OpenAI launches model spec for developers"},
+		{"Google launches Gemini 2.0 Flash model", "Google launches Veo 2 video model"},
+		{"Meta launches Llama 4 open model", "Meta launches Meta AI app with Llama 4 model"},
+	} {
+		pairs = append(pairs, [2]RawArticle{{SourceName: "a.com", Title: titles[0]}, {SourceName: "b.com", Title: titles[1]}})
+	}
+	for _, p := range pairs {
+		if n := len(dedup(p[:], DefaultDedupOptions())); n != 2 {
+			t.Errorf("%q and %q merged into one story", p[0].Title, p[1].Title)
+		}
+	}
+}
+
+func TestShingle(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		// A word next to CJK text stands alone.
+		{"OpenAI发布GPT-4o大模型 for the ChatGPT", []string{"openai", "发布", "gpt 4o", "大模", "模型", "chatgpt"}},
+		// Stop words are dropped before words are paired.
+		{"OpenAI launches the new model", []string{"openai launches", "launches model"}},
+		{"模", []string{"模"}},
+		{"the of", nil},
+	}
+	for _, tt := range tests {
+		want := make(map[string]bool)
+		for _, w := range tt.want {
+			want[w] = true
+		}
+		if got := shingle(tt.text); !reflect.DeepEqual(got, want) {
+			t.Errorf("shingle(%q) = %v, want %v", tt.text, got, want)
+		}
+	}
+}
+
+func TestRankBuzz(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	story := func(source, title string) RawArticle {
+		return RawArticle{SourceName: source, Title: title, Category: "global", PublishDate: now.Add(-2 * time.Hour),
+			Summary: "Mistral released Large 2, a 123B parameter model with a 128k context window, under a research license."}
+	}
+	articles := []RawArticle{
+		// A single blog post, fresher than the story below.
+		{SourceName: "blog.example.com", Title: "Notes on fine-tuning small models", Category: "global", PublishDate: now.Add(-time.Hour),
+			Summary: "What we learned from fine-tuning a 7B model on support tickets."},
+		story("techcrunch.com", "Mistral releases Large 2 model"),
+		story("theverge.com", "Mistral’s Large 2 model is here"),
+		story("venturebeat.com", "Mistral launches Large 2 with 128k context"),
+		story("arstechnica.com", "Mistral Large 2 arrives with 123B parameters"),
+	}
+	weights := map[string]float64{"blog.example.com": 1, "techcrunch.com": 1, "theverge.com": 1, "venturebeat.com": 1, "arstechnica.com": 1}
+
+	ranked := Rank(articles, weights, KeywordOptions{}, DefaultRankingOptions(), now)
+	if len(ranked) != 2 {
+		t.Fatalf("got %d stories, want 2", len(ranked))
+	}
+	if top := ranked[0]; top.SourceName != "techcrunch.com" || top.Components.Sources != 4 || top.Components.Buzz != 1 {
+		t.Errorf("top = %s with %d sources, buzz %.2f; want techcrunch.com with 4 sources, buzz 1",
+			top.SourceName, top.Components.Sources, top.Components.Buzz)
+	}
+
+	opts := DefaultRankingOptions()
+	opts.Defaults.Buzz = 0
+	if top := Rank(articles, weights, KeywordOptions{}, opts, now)[0]; top.SourceName != "blog.example.com" {
+		t.Errorf("without buzz the top story is %s, want the fresher blog.example.com", top.SourceName)
+	}
+}
@@ -0,0 +1,158 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// DiversityOptions tune how the top N of a category are picked from its
+// ranked candidates.
+type DiversityOptions struct {
+	MaxPerSource int `yaml:"max_per_source" json:"max_per_source"` // articles per feed in the top N, 0 = no limit
+	MaxPerDomain int `yaml:"max_per_domain" json:"max_per_domain"` // articles per site (registrable domain), 0 = no limit
+	// Lambda trades score against novelty: each pick maximises
+	// Lambda·score − (1−Lambda)·(similarity to the closest article already
+	// picked). 1 picks by score alone.
+	Lambda float64 `yaml:"lambda" json:"lambda"`
+}
+
+// DefaultDiversityOptions returns the options used when the sources file
+// does not set them.
+func DefaultDiversityOptions() DiversityOptions {
+	return DiversityOptions{MaxPerSource: 2, MaxPerDomain: 2, Lambda: 0.8}
+}
+
+func (o DiversityOptions) validate() error {
+	var errs []error
+	if o.MaxPerSource < 0 || o.MaxPerDomain < 0 {
+		errs = append(errs, errors.New("ranking.diversity: limits must not be negative"))
+	}
+	if o.Lambda < 0 || o.Lambda > 1 {
+		errs = append(errs, fmt.Errorf("ranking.diversity.lambda %.2f out of range [0, 1]", o.Lambda))
+	}
+	return errors.Join(errs...)
+}
+
+// Diversify reorders ranked candidates (highest score first) so that the
+// first topN are picked greedily by maximal marginal relevance within the
+// per-source and per-domain limits. The limits are only relaxed when too few
+// candidates remain within them to fill topN. The rest follow in their
+// original order. Ties go to the higher-ranked candidate, so the result is
+// deterministic.
+func Diversify(ranked []RawArticle, topN int, opts DiversityOptions) []RawArticle {
+	if topN <= 0 || len(ranked) <= 1 {
+		return ranked
+	}
+
+	var sigs []*signature
+	if opts.Lambda < 1 {
+		sigs = make([]*signature, len(ranked))
+		for i, a := range ranked {
+			sigs[i] = minHash(a.Title + " " + a.Summary)
+		}
+	}
+
+	picked := make([]bool, len(ranked))
+	var order []int
+	perSource := make(map[string]int)
+	perDomain := make(map[string]int)
+	relaxed := false
+
+	for len(order) < topN && len(order) < len(ranked) {
+		best, bestValue := -1, math.Inf(-1)
+		for i, a := range ranked {
+			if picked[i] {
+				continue
+			}
+			if !relaxed && !opts.allows(perSource[articleSource(a)], perDomain[articleDomain(a)]) {
+				continue
+			}
+			v := ranked[i].Score
+			if sigs != nil {
+				v = opts.Lambda*v - (1-opts.Lambda)*maxSimilarity(sigs[i], sigs, order)
+			}
+			if v > bestValue {
+				best, bestValue = i, v
+			}
+		}
+		if best < 0 {
+			if relaxed {
+				break
+			}
+			relaxed = true
+			continue
+		}
+		picked[best] = true
+		order = append(order, best)
+		perSource[articleSource(ranked[best])]++
+		perDomain[articleDomain(ranked[best])]++
+	}
+
+	out := make([]RawArticle, 0, len(ranked))
+	for _, i := range order {
+		out = append(out, ranked[i])
+	}
+	for i, a := range ranked {
+		if !picked[i] {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+// allows reports whether one more article fits a source and a domain that
+// already have the given number of picks.
+func (o DiversityOptions) allows(source, domain int) bool {
+	return (o.MaxPerSource == 0 || source < o.MaxPerSource) &&
+		(o.MaxPerDomain == 0 || domain < o.MaxPerDomain)
+}
+
+// maxSimilarity is the highest similarity of sig to the picked signatures.
+func maxSimilarity(sig *signature, sigs []*signature, picked []int) float64 {
+	var m float64
+	for _, i := range picked {
+		m = max(m, similarity(sig, sigs[i]))
+	}
+	return m
+}
+
+// articleSource is the feed an article came from.
+func articleSource(a RawArticle) string {
+	if a.Feed != "" {
+		return a.Feed
+	}
+	return a.SourceName
+}
+
+// articleDomain is the registrable domain of an article's link, so
+// tc.techcrunch.com and techcrunch.com count as one site.
+func articleDomain(a RawArticle) string {
+	host := extractDomainFromURL(a.SourceURL)
+	if host == "" {
+		return a.SourceName
+	}
+	return baseDomain(host)
+}
+
+// secondLevelSuffixes are public suffixes with two labels that are common
+// among news sites.
+var secondLevelSuffixes = map[string]bool{
+	"com.cn": true, "net.cn": true, "org.cn": true, "gov.cn": true, "edu.cn": true,
+	"com.hk": true, "com.tw": true, "co.uk": true, "co.jp": true, "com.au": true,
+}
+
+// baseDomain returns the last two labels of host, or three when the last two
+// are a second-level public suffix such as com.cn.
+func baseDomain(host string) string {
+	labels := strings.Split(strings.ToLower(host), ".")
+	n := 2
+	if len(labels) >= 3 && secondLevelSuffixes[strings.Join(labels[len(labels)-2:], ".")] {
+		n = 3
+	}
+	if len(labels) <= n {
+		return strings.Join(labels, ".")
+	}
+	return strings.Join(labels[len(labels)-n:], ".")
+}
@@ -0,0 +1,135 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiversify(t *testing.T) {
+	// art builds a candidate; titles double as IDs in the expectations.
+	art := func(title, feed, link string, score float64) RawArticle {
+		return RawArticle{Title: title, Feed: feed, SourceURL: link, SourceName: extractDomainFromURL(link), Score: score}
+	}
+	jqzx := func(title string, score float64) RawArticle {
+		return art(title, "机器之心", "https://www.jiqizhixin.com/articles/"+title, score)
+	}
+
+	tests := []struct {
+		name   string
+		ranked []RawArticle
+		topN   int
+		opts   DiversityOptions
+		want   []string
+	}{
+		{
+			name: "no limits keeps score order",
+			ranked: []RawArticle{
+				jqzx("a", 0.9), jqzx("b", 0.8), jqzx("c", 0.7), art("d", "36氪", "https://36kr.com/p/d", 0.6),
+			},
+			topN: 3,
+			opts: DiversityOptions{Lambda: 1},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "per-source limit lets other feeds in",
+			ranked: []RawArticle{
+				jqzx("a", 0.95), jqzx("b", 0.9), jqzx("c", 0.85), jqzx("d", 0.8), jqzx("e", 0.75),
+				art("f", "36氪", "https://36kr.com/p/f", 0.6),
+				art("g", "InfoQ中国", "https://www.infoq.cn/article/g", 0.5),
+				art("h", "36氪", "https://36kr.com/p/h", 0.4),
+			},
+			topN: 5,
+			opts: DiversityOptions{MaxPerSource: 2, Lambda: 1},
+			want: []string{"a", "b", "f", "g", "h", "c", "d", "e"},
+		},
+		{
+			name: "per-domain limit spans feeds of one site",
+			ranked: []RawArticle{
+				art("a", "TechCrunch AI", "https://techcrunch.com/a", 0.9),
+				art("b", "TechCrunch Startups", "https://tc.techcrunch.com/b", 0.8),
+				art("c", "TechCrunch Apps", "https://techcrunch.com/c", 0.7),
+				art("d", "The Verge AI", "https://www.theverge.com/d", 0.6),
+			},
+			topN: 3,
+			opts: DiversityOptions{MaxPerDomain: 2, Lambda: 1},
+			want: []string{"a", "b", "d", "c"},
+		},
+		{
+			name: "second-level suffix counts as one site",
+			ranked: []RawArticle{
+				art("a", "新浪科技", "https://tech.sina.com.cn/a", 0.9),
+				art("b", "新浪财经", "https://finance.sina.com.cn/b", 0.8),
+				art("c", "网易科技", "https://tech.163.com/c", 0.7),
+			},
+			topN: 2,
+			opts: DiversityOptions{MaxPerDomain: 1, Lambda: 1},
+			want: []string{"a", "c", "b"},
+		},
+		{
+			name: "limits are relaxed when too few candidates fit",
+			ranked: []RawArticle{
+				jqzx("a", 0.9), jqzx("b", 0.8), jqzx("c", 0.7), jqzx("d", 0.6),
+			},
+			topN: 3,
+			opts: DiversityOptions{MaxPerSource: 1, Lambda: 1},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name: "similar article is pushed below a novel one",
+			ranked: []RawArticle{
+				{Title: "Nvidia unveils Blackwell B200 GPU for AI training at GTC", Summary: "Nvidia announced the Blackwell B200 GPU and GB200 superchip at GTC.", Feed: "x", Score: 0.9},
+				{Title: "Nvidia Blackwell B200 GPU: specs and price of the GTC AI chip", Summary: "The Blackwell B200 GPU announced at GTC pairs two dies for AI training.", Feed: "y", Score: 0.85},
+				{Title: "EU lawmakers approve the AI Act", Summary: "The European Parliament voted to adopt the AI Act.", Feed: "z", Score: 0.8},
+			},
+			topN: 2,
+			opts: DiversityOptions{Lambda: 0.5},
+			want: []string{"Nvidia unveils Blackwell B200 GPU for AI training at GTC", "EU lawmakers approve the AI Act",
+				"Nvidia Blackwell B200 GPU: specs and price of the GTC AI chip"},
+		},
+		{
+			name: "ties go to the higher-ranked candidate",
+			ranked: []RawArticle{
+				jqzx("a", 0.5), art("b", "36氪", "https://36kr.com/p/b", 0.5), jqzx("c", 0.5), art("d", "36氪", "https://36kr.com/p/d", 0.5),
+			},
+			topN: 3,
+			opts: DiversityOptions{MaxPerSource: 1, Lambda: 0.8},
+			want: []string{"a", "b", "c", "d"},
+		},
+		{
+			name:   "topN larger than the pool",
+			ranked: []RawArticle{jqzx("a", 0.9), jqzx("b", 0.8)},
+			topN:   5,
+			opts:   DefaultDiversityOptions(),
+			want:   []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, a := range Diversify(tt.ranked, tt.topN, tt.opts) {
+				got = append(got, a.Title)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBaseDomain(t *testing.T) {
+	tests := map[string]string{
+		"techcrunch.com":        "techcrunch.com",
+		"tc.techcrunch.com":     "techcrunch.com",
+		"tech.sina.com.cn":      "sina.com.cn",
+		"www.bbc.co.uk":         "bbc.co.uk",
+		"36kr.com":              "36kr.com",
+		"feeds.arstechnica.com": "arstechnica.com",
+		"localhost":             "localhost",
+	}
+	for host, want := range tests {
+		if got := baseDomain(host); got != want {
+			t.Errorf("baseDomain(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package fetcher
+
+import (
+	"fmt"
+	"log"
+	"top-ai-news/internal/database"
+)
+
+// Edition states. A draft is rewritten by every fetch; published marks an
+// edition an editor has released; a locked edition is only rewritten by a
+// forced fetch.
+const (
+	EditionDraft     = "draft"
+	EditionPublished = "published"
+	EditionLocked    = "locked"
+)
+
+// ErrEditionLocked is returned when a run would rewrite only locked editions,
+// and by the database when an edition is locked while a run is fetching.
+var ErrEditionLocked = database.ErrEditionLocked
+
+// ValidEditionState reports whether s is a known edition state.
+func ValidEditionState(s string) bool {
+	return s == EditionDraft || s == EditionPublished || s == EditionLocked
+}
+
+// EditionState returns the state of date's edition; dates without one are drafts.
+func (f *Fetcher) EditionState(date string) (string, error) {
+	state, err := f.db.GetEditionState(date)
+	if err != nil {
+		return "", err
+	}
+	if state == "" {
+		state = EditionDraft
+	}
+	return state, nil
+}
+
+// SetEditionState changes the state of date's edition.
+func (f *Fetcher) SetEditionState(date, state string) error {
+	if !ValidEditionState(state) {
+		return fmt.Errorf("unknown edition state %q", state)
+	}
+	if _, _, err := f.dayBounds(date); err != nil {
+		return err
+	}
+	return f.db.SetEditionState(date, state)
+}
+
+// writableDates drops the locked dates from dates unless force is set.
+func (f *Fetcher) writableDates(dates []string, force bool) ([]string, error) {
+	if force {
+		return dates, nil
+	}
+	var out []string
+	for _, date := range dates {
+		state, err := f.EditionState(date)
+		if err != nil {
+			return nil, err
+		}
+		if state == EditionLocked {
+			log.Printf("🔒 %s 已锁定，跳过", date)
+			continue
+		}
+		out = append(out, date)
+	}
+	return out, nil
+}
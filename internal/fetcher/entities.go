@@ -0,0 +1,227 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Entity types.
+const (
+	EntityOrg    = "org"
+	EntityModel  = "model"
+	EntityPerson = "person"
+)
+
+// Entity is an entry of the gazetteer: an organization, model or person with
+// the names it goes by.
+type Entity struct {
+	Name    string      `yaml:"name" json:"name"`                           // canonical name, stored with articles
+	Type    string      `yaml:"type" json:"type"`                           // org, model or person
+	Aliases KeywordList `yaml:"aliases,omitempty" json:"aliases,omitempty"` // other names, matched like keywords
+	Enabled *bool       `yaml:"enabled,omitempty" json:"-"`                 // false removes a built-in entry
+
+	terms KeywordList // Name and Aliases, compiled
+}
+
+// Match reports whether text mentions the entity by its name or an alias.
+func (e *Entity) Match(text string) bool {
+	return e.terms.Any(text)
+}
+
+// names returns the lower-case plain names of the entity, for lookups.
+func (e *Entity) names() []string {
+	names := []string{strings.ToLower(e.Name)}
+	for _, a := range e.Aliases {
+		if !a.Regex {
+			names = append(names, strings.TrimSuffix(a.Term, "*"))
+		}
+	}
+	return names
+}
+
+func (e *Entity) compile(where string) error {
+	var errs []error
+	if strings.TrimSpace(e.Name) == "" {
+		errs = append(errs, fmt.Errorf("%s: name is required", where))
+	}
+	switch e.Type {
+	case EntityOrg, EntityModel, EntityPerson:
+	default:
+		errs = append(errs, fmt.Errorf("%s: unknown type %q (want org, model or person)", where, e.Type))
+	}
+	errs = append(errs, e.Aliases.compile(where+".aliases"))
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	name := KeywordTerm{Term: e.Name, Weight: 1}
+	name.compile()
+	e.terms = append(KeywordList{name}, e.Aliases...)
+	return nil
+}
+
+// Gazetteer lists the entities articles are annotated with.
+type Gazetteer []Entity
+
+// alias turns names into weight 1 alias terms.
+func alias(names ...string) KeywordList {
+	return terms(names)
+}
+
+// DefaultGazetteer returns the compiled-in entities. It panics if one of them
+// does not compile.
+func DefaultGazetteer() Gazetteer {
+	g := Gazetteer{
+		// Organizations
+		{Name: "OpenAI", Type: EntityOrg},
+		{Name: "Anthropic", Type: EntityOrg},
+		{Name: "Google", Type: EntityOrg, Aliases: alias("谷歌", "Alphabet")},
+		{Name: "Google DeepMind", Type: EntityOrg, Aliases: alias("DeepMind")},
+		{Name: "Microsoft", Type: EntityOrg, Aliases: alias("微软")},
+		{Name: "Meta", Type: EntityOrg, Aliases: alias("Facebook")},
+		{Name: "Apple", Type: EntityOrg, Aliases: alias("苹果公司")},
+		{Name: "Amazon", Type: EntityOrg, Aliases: alias("亚马逊", "AWS")},
+		{Name: "Nvidia", Type: EntityOrg, Aliases: alias("英伟达")},
+		{Name: "xAI", Type: EntityOrg},
+		{Name: "Mistral AI", Type: EntityOrg, Aliases: alias("Mistral")},
+		{Name: "Hugging Face", Type: EntityOrg, Aliases: alias("HuggingFace")},
+		{Name: "DeepSeek", Type: EntityOrg, Aliases: alias("深度求索")},
+		{Name: "Alibaba", Type: EntityOrg, Aliases: alias("阿里巴巴", "阿里云", "阿里")},
+		{Name: "Baidu", Type: EntityOrg, Aliases: alias("百度")},
+		{Name: "Tencent", Type: EntityOrg, Aliases: alias("腾讯")},
+		{Name: "ByteDance", Type: EntityOrg, Aliases: alias("字节跳动")},
+		{Name: "Huawei", Type: EntityOrg, Aliases: alias("华为")},
+		{Name: "Moonshot AI", Type: EntityOrg, Aliases: alias("月之暗面")},
+		{Name: "Zhipu AI", Type: EntityOrg, Aliases: alias("智谱")},
+		{Name: "MiniMax", Type: EntityOrg},
+		{Name: "iFlytek", Type: EntityOrg, Aliases: alias("科大讯飞")},
+
+		// Models and assistants
+		{Name: "GPT", Type: EntityModel},
+		{Name: "ChatGPT", Type: EntityModel},
+		{Name: "Claude", Type: EntityModel},
+		{Name: "Gemini", Type: EntityModel},
+		{Name: "Llama", Type: EntityModel},
+		{Name: "Grok", Type: EntityModel},
+		{Name: "Sora", Type: EntityModel},
+		{Name: "Copilot", Type: EntityModel},
+		{Name: "Qwen", Type: EntityModel, Aliases: alias("通义千问", "通义", "千问")},
+		{Name: "ERNIE", Type: EntityModel, Aliases: alias("文心一言", "文心大模型", "文心", "ERNIE Bot")},
+		{Name: "Hunyuan", Type: EntityModel, Aliases: alias("混元")},
+		{Name: "Doubao", Type: EntityModel, Aliases: alias("豆包")},
+		{Name: "Kimi", Type: EntityModel},
+		{Name: "GLM", Type: EntityModel, Aliases: alias("ChatGLM", "智谱清言")},
+
+		// People
+		{Name: "Sam Altman", Type: EntityPerson, Aliases: alias("Altman", "奥特曼")},
+		{Name: "Dario Amodei", Type: EntityPerson, Aliases: alias("Amodei")},
+		{Name: "Demis Hassabis", Type: EntityPerson, Aliases: alias("Hassabis", "哈萨比斯")},
+		{Name: "Jensen Huang", Type: EntityPerson, Aliases: alias("黄仁勋")},
+		{Name: "Elon Musk", Type: EntityPerson, Aliases: alias("Musk", "马斯克")},
+		{Name: "Mark Zuckerberg", Type: EntityPerson, Aliases: alias("Zuckerberg", "扎克伯格")},
+		{Name: "Sundar Pichai", Type: EntityPerson, Aliases: alias("Pichai", "皮查伊")},
+		{Name: "Satya Nadella", Type: EntityPerson, Aliases: alias("Nadella", "纳德拉")},
+		{Name: "Liang Wenfeng", Type: EntityPerson, Aliases: alias("梁文锋")},
+		{Name: "Robin Li", Type: EntityPerson, Aliases: alias("李彦宏")},
+		{Name: "Yann LeCun", Type: EntityPerson, Aliases: alias("LeCun", "杨立昆")},
+		{Name: "Andrej Karpathy", Type: EntityPerson, Aliases: alias("Karpathy")},
+		{Name: "Ilya Sutskever", Type: EntityPerson, Aliases: alias("Sutskever")},
+	}
+	if err := g.compile(); err != nil {
+		panic("default gazetteer: " + err.Error())
+	}
+	return g
+}
+
+// merge returns g with the entries of extra: an entry named like one of g
+// replaces it, or removes it if disabled; the others are appended.
+func (g Gazetteer) merge(extra Gazetteer) Gazetteer {
+	out := append(Gazetteer(nil), g...)
+	for _, e := range extra {
+		i := out.index(e.Name)
+		switch {
+		case e.Enabled != nil && !*e.Enabled:
+			if i >= 0 {
+				out = append(out[:i], out[i+1:]...)
+			}
+		case i >= 0:
+			out[i] = e
+		default:
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// index returns the position of the entry named name, or -1.
+func (g Gazetteer) index(name string) int {
+	for i := range g {
+		if strings.EqualFold(g[i].Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lookup finds an entity by its canonical name or a plain alias, ignoring case.
+func (g Gazetteer) Lookup(name string) (Entity, bool) {
+	if i := g.index(name); i >= 0 {
+		return g[i], true
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, e := range g {
+		for _, n := range e.names() {
+			if n == name {
+				return e, true
+			}
+		}
+	}
+	return Entity{}, false
+}
+
+// Extract returns the canonical names of the entities a mentions, in
+// gazetteer order.
+func (g Gazetteer) Extract(a RawArticle) []string {
+	text := a.Title + " " + a.Summary
+	var names []string
+	for i := range g {
+		if g[i].Match(text) {
+			names = append(names, g[i].Name)
+		}
+	}
+	return names
+}
+
+// annotate extracts the entities of ranked articles and the other sources'
+// articles of their stories.
+func (g Gazetteer) annotate(ranked []RawArticle) {
+	for i := range ranked {
+		ranked[i].Entities = g.Extract(ranked[i])
+		for j := range ranked[i].Also {
+			ranked[i].Also[j].Entities = g.Extract(ranked[i].Also[j])
+		}
+	}
+}
+
+// compile prepares every entry and reports the invalid ones.
+func (g Gazetteer) compile() error {
+	var errs []error
+	seen := make(map[string]int)
+	for i := range g {
+		where := fmt.Sprintf("entities[%d]", i)
+		if g[i].Name != "" {
+			where = fmt.Sprintf("%s %q", where, g[i].Name)
+			key := strings.ToLower(g[i].Name)
+			if j, dup := seen[key]; dup {
+				errs = append(errs, fmt.Errorf("%s: duplicate name (already defined at entities[%d])", where, j))
+			} else {
+				seen[key] = i
+			}
+		}
+		if g[i].Enabled != nil && !*g[i].Enabled {
+			continue
+		}
+		errs = append(errs, g[i].compile(where))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,70 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultGazetteer(t *testing.T) {
+	g := DefaultGazetteer()
+	tests := []struct {
+		title string
+		want  []string
+	}{
+		{"OpenAI launches GPT-5 with a 1M token context window", []string{"OpenAI", "GPT"}},
+		{"百度发布文心一言4.0，李彦宏称其能力不输GPT-4", []string{"Baidu", "GPT", "ERNIE", "Robin Li"}},
+		{"Baidu opens ERNIE Bot to the public", []string{"Baidu", "ERNIE"}},
+		{"阿里云通义千问开源新模型", []string{"Alibaba", "Qwen"}},
+		{"DeepSeek-R1 tops the app store", []string{"DeepSeek"}},
+		// Latin names match whole words only.
+		{"Metadata standards for chatbots", nil},
+	}
+	for _, tt := range tests {
+		if got := g.Extract(RawArticle{Title: tt.title}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: entities %v, want %v", tt.title, got, tt.want)
+		}
+	}
+
+	for _, name := range []string{"ERNIE", "文心一言", "ernie bot"} {
+		if e, ok := g.Lookup(name); !ok || e.Name != "ERNIE" {
+			t.Errorf("Lookup(%q) = %q, %v; want ERNIE", name, e.Name, ok)
+		}
+	}
+	if _, ok := g.Lookup("Perplexity"); ok {
+		t.Error("Lookup found an entity that is not in the gazetteer")
+	}
+}
+
+func TestGazetteerConfig(t *testing.T) {
+	set := mustParseSources(t, `
+entities:
+  - {name: Perplexity, type: org, aliases: [Perplexity AI]}
+  - {name: Qwen, type: model, aliases: [千问]}
+  - {name: Copilot, enabled: false}`+oneSource)
+	g := set.Entities
+	if got := g.Extract(RawArticle{Title: "Perplexity AI raises again"}); !reflect.DeepEqual(got, []string{"Perplexity"}) {
+		t.Errorf("entities %v, want [Perplexity]", got)
+	}
+	if got := g.Extract(RawArticle{Title: "通义千问新版本上线"}); !reflect.DeepEqual(got, []string{"Qwen"}) {
+		t.Errorf("entities %v, want [Qwen] from the replaced entry's aliases", got)
+	}
+	if got := g.Extract(RawArticle{Title: "Microsoft Copilot adds voice"}); !reflect.DeepEqual(got, []string{"Microsoft"}) {
+		t.Errorf("entities %v, want the disabled entry gone", got)
+	}
+	if _, ok := g.Lookup("通义"); ok {
+		t.Error("alias of the replaced built-in entry still resolves")
+	}
+	if len(g) != len(DefaultGazetteer()) {
+		t.Errorf("%d entities, want one added and one removed", len(g))
+	}
+}
+
+func TestGazetteerConfigErrors(t *testing.T) {
+	wantSourcesErrors(t, `
+entities:
+  - {name: Perplexity, type: company}
+  - {type: org}
+  - {name: perplexity, type: org, aliases: [{term: '(x', regex: true}]}`+oneSource,
+		`unknown type "company"`, "entities[1]: name is required",
+		`entities[2] "perplexity": duplicate name`, `entities[2] "perplexity".aliases[0]`)
+}
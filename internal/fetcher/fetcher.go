@@ -2,53 +2,172 @@ package fetcher
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strings"
 	"sync"
 	"time"
 	"top-ai-news/internal/database"
 	"top-ai-news/internal/model"
+	"top-ai-news/internal/urlnorm"
 )
 
 type Fetcher struct {
 	db     *database.DB
+	loc    *time.Location // timezone that defines a day's boundaries
 	stopCh chan struct{}
 	mu     sync.Mutex
+
+	sourcesPath string
+	sourcesMu   sync.RWMutex
+	active      *SourceSet
+	reloadErr   error
+
+	jobs jobQueue
+}
+
+// New creates a Fetcher. With an empty sourcesPath the compiled-in feeds are
+// used; otherwise the file is loaded (and created from the defaults if it does
+// not exist yet) and can later be reloaded with ReloadSources. loc is the
+// edition timezone that decides when a day starts; nil means the local zone.
+func New(db *database.DB, sourcesPath string, loc *time.Location) (*Fetcher, error) {
+	if loc == nil {
+		loc = time.Local
+	}
+	f := &Fetcher{
+		db:          db,
+		loc:         loc,
+		stopCh:      make(chan struct{}),
+		sourcesPath: sourcesPath,
+	}
+	if sourcesPath != "" {
+		if err := ensureSourcesFile(sourcesPath); err != nil {
+			return nil, err
+		}
+	}
+	set, err := LoadSources(sourcesPath)
+	if err != nil {
+		return nil, err
+	}
+	f.active = set
+	return f, nil
+}
+
+// Trigger identifies what started a fetch run.
+type Trigger string
+
+const (
+	TriggerScheduler Trigger = "scheduler"
+	TriggerAPI       Trigger = "api"
+	TriggerCLI       Trigger = "cli"
+)
+
+// FetchAndStore fetches RSS feeds concurrently, ranks articles, and stores the top N of each category.
+// Each call is recorded as a fetch run together with the outcome of every source.
+// It blocks until the run is done; the server uses Enqueue instead.
+// A locked edition is only rewritten when force is set.
+func (f *Fetcher) FetchAndStore(date string, trigger Trigger, force bool) error {
+	_, err := f.run(runRequest{dates: []string{date}, trigger: trigger, force: force}, nil)
+	return err
+}
+
+// Backfill fetches every feed once and rebuilds the rankings of each day from
+// from to to (inclusive). Only items published on a day are ranked for it, with
+// timeliness measured from the end of that day. Locked days are skipped unless
+// force is set.
+func (f *Fetcher) Backfill(from, to string, trigger Trigger, force bool) (int, error) {
+	dates, err := DateRange(from, to)
+	if err != nil {
+		return 0, err
+	}
+	return f.run(runRequest{dates: dates, trigger: trigger, force: force}, nil)
 }
 
-func New(db *database.DB) *Fetcher {
-	return &Fetcher{
-		db:     db,
-		stopCh: make(chan struct{}),
+// runRequest describes the dates a fetch run rebuilds.
+type runRequest struct {
+	dates   []string
+	trigger Trigger
+	force   bool // rewrite locked editions too
+}
+
+// label names the run: a single date, or "from..to" for a backfill.
+func (r runRequest) label() string {
+	if len(r.dates) == 1 {
+		return r.dates[0]
 	}
+	return r.dates[0] + ".." + r.dates[len(r.dates)-1]
 }
 
-// FetchAndStore fetches RSS feeds concurrently, ranks articles, and stores top 5 per category.
-func (f *Fetcher) FetchAndStore(date string) error {
+// key identifies requests that can share a job: same dates, same force flag.
+func (r runRequest) key() string {
+	if r.force {
+		return r.label() + "!force"
+	}
+	return r.label()
+}
+
+// run performs a fetch run and reports per-source progress to job, which may be nil.
+// It returns the number of stored news items.
+func (f *Fetcher) run(req runRequest, job *Job) (stored int, err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
 
+	set := f.Sources()
+	ranking, _ := json.Marshal(set.Ranking)
+	runID, err := f.db.CreateFetchRun(req.label(), string(req.trigger), string(ranking))
+	if err != nil {
+		log.Printf("记录抓取任务失败: %v", err)
+	}
+	defer func() {
+		if runID == 0 {
+			return
+		}
+		status, msg := "succeeded", ""
+		if err != nil {
+			status, msg = "failed", err.Error()
+		}
+		if err := f.db.FinishFetchRun(runID, status, stored, msg); err != nil {
+			log.Printf("记录抓取任务失败: %v", err)
+		}
+	}()
+
+	dates, err := f.writableDates(req.dates, req.force)
+	if err != nil {
+		return 0, err
+	}
+	if len(dates) == 0 {
+		return 0, fmt.Errorf("%s: %w", req.label(), ErrEditionLocked)
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	allFeeds := append(DomesticFeeds(), GlobalFeeds()...)
-	sourceWeights := BuildSourceWeights(allFeeds)
+	job.begin(runID, set.Feeds)
+	byCategory := f.fetchAll(ctx, set, runID, job)
 
-	// Fetch all feeds concurrently
-	type fetchResult struct {
-		source   FeedSource
-		articles []RawArticle
-		err      error
+	for _, date := range dates {
+		n, err := f.storeDay(runID, date, byCategory, set, req.force)
+		if err != nil {
+			return stored, err
+		}
+		stored += n
 	}
+	return stored, nil
+}
 
-	results := make(chan fetchResult, len(allFeeds))
+// fetchAll fetches all feeds concurrently and groups the articles by category.
+func (f *Fetcher) fetchAll(ctx context.Context, set *SourceSet, runID int64, job *Job) map[string][]RawArticle {
+	byCategory := make(map[string][]RawArticle)
+	results := make(chan fetchResult, len(set.Feeds))
 	var wg sync.WaitGroup
 
-	for _, feed := range allFeeds {
+	for _, feed := range set.Feeds {
 		wg.Add(1)
 		go func(src FeedSource) {
 			defer wg.Done()
-			articles, err := FetchFeed(ctx, src)
-			results <- fetchResult{source: src, articles: articles, err: err}
+			results <- f.fetchSource(ctx, src, set.Keywords.For(src.Category), set.Fetch)
 		}(feed)
 	}
 
@@ -58,88 +177,152 @@ func (f *Fetcher) FetchAndStore(date string) error {
 		close(results)
 	}()
 
-	// Collect results, split by category
-	var domestic, global []RawArticle
 	for r := range results {
+		rec := r.record(runID)
+		job.sourceDone(rec)
+		if runID != 0 {
+			if err := f.db.InsertFetchRunSource(rec); err != nil {
+				log.Printf("记录 %s 抓取结果失败: %v", r.source.Name, err)
+			}
+		}
+		if r.skipped {
+			log.Printf("⏸ %s 熔断中，跳过本次抓取", r.source.Name)
+			continue
+		}
 		if r.err != nil {
 			log.Printf("⚠ 抓取 %s 失败: %v", r.source.Name, r.err)
 			continue
 		}
-		log.Printf("✓ 从 %s 获取 %d 篇文章", r.source.Name, len(r.articles))
-		for _, a := range r.articles {
-			if a.Category == "domestic" {
-				domestic = append(domestic, a)
-			} else {
-				global = append(global, a)
-			}
+		if r.NotModified {
+			log.Printf("✓ %s 未更新 (304)，沿用缓存的 %d 篇文章", r.source.Name, len(r.Articles))
+		} else {
+			log.Printf("✓ 从 %s 获取 %d 篇文章", r.source.Name, len(r.Articles))
+		}
+		for _, a := range r.Articles {
+			byCategory[a.Category] = append(byCategory[a.Category], a)
 		}
 	}
+	return byCategory
+}
 
-	// Rank and select top 5 per category
-	topDomestic := RankAndSelect(domestic, 5, sourceWeights)
-	topGlobal := RankAndSelect(global, 5, sourceWeights)
-
-	// Delete existing news for the date to avoid duplicates
-	if err := f.db.DeleteNewsByDate(date); err != nil {
-		return err
+// storeDay ranks the articles for date and replaces that day's news. Every
+// deduplicated candidate is stored with its score components under runID.
+// Only articles published within the day in the edition timezone are
+// considered, up to now for a day that has not ended. Timeliness is scored
+// relative to now, or to the end of a day that has ended. A day whose
+// edition was locked during the fetch is skipped unless force is set.
+func (f *Fetcher) storeDay(runID int64, date string, byCategory map[string][]RawArticle, set *SourceSet, force bool) (int, error) {
+	start, end, err := f.dayBounds(date)
+	if err != nil {
+		return 0, err
 	}
+	now := time.Now()
+	if end.After(now) {
+		end = now
+	}
+	now = end
 
-	// Store results
-	stored := 0
-	for i, a := range topDomestic {
-		n := rawToNews(a, "domestic", date, i+1)
-		if _, err := f.db.InsertNews(n); err != nil {
-			log.Printf("保存国内新闻失败: %v", err)
-		} else {
-			stored++
-		}
+	// Rank all candidates; the top N of each category make the ranking.
+	// Articles are upserted by canonical URL so they keep their IDs (and
+	// comments); articles that fell out of the ranking are marked dropped.
+	var cands []model.Candidate
+	var counts []string
+	for _, c := range set.Categories {
+		ranked := articlesBetween(byCategory[c.Name], start, end)
+		ranked = Rank(ranked, set.weights, set.Keywords, set.Ranking, now)
+		ranked = Diversify(ranked, c.TopN, set.Ranking.Diversity)
+		set.tagger.tag(ranked)
+		set.Entities.annotate(ranked)
+		cands = append(cands, toCandidates(runID, ranked, c.Name, date, c.TopN)...)
+		counts = append(counts, fmt.Sprintf("%s: %d", c.Label, min(len(ranked), c.TopN)))
 	}
-	for i, a := range topGlobal {
-		n := rawToNews(a, "global", date, i+1)
-		if _, err := f.db.InsertNews(n); err != nil {
-			log.Printf("保存全球新闻失败: %v", err)
-		} else {
-			stored++
-		}
+	// Saving an empty pool would drop the whole ranking, e.g. when a backfill
+	// runs after the day's items have aged out of the feeds.
+	if len(cands) == 0 {
+		log.Printf("⚠ %s 没有找到任何文章，保留现有榜单", date)
+		return 0, nil
+	}
+	change, err := f.db.SaveRanking(runID, date, cands, force)
+	if errors.Is(err, ErrEditionLocked) {
+		log.Printf("🔒 %s 已锁定，跳过", date)
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("save %s: %w", date, err)
 	}
+	stored := change.Inserted + change.Updated
 
-	log.Printf("✓ 共保存 %d 条新闻 (国内: %d, 全球: %d)", stored, len(topDomestic), len(topGlobal))
-	return nil
+	if err := f.db.EnsureEdition(date); err != nil {
+		log.Printf("记录 %s 日报状态失败: %v", date, err)
+	}
+
+	log.Printf("✓ %s 共保存 %d 条新闻 (%s；新增 %d，更新 %d，移出 %d)",
+		date, stored, strings.Join(counts, ", "), change.Inserted, change.Updated, change.Dropped)
+	return stored, nil
 }
 
-// StartScheduler starts a background goroutine that fetches news on startup (if needed)
-// and refreshes periodically at the given interval.
-func (f *Fetcher) StartScheduler(interval time.Duration) {
-	go func() {
-		// Initial fetch: check if today has data
-		today := time.Now().Format("2006-01-02")
-		has, _ := f.db.HasNewsForDate(today)
-		if !has {
-			log.Println("📡 首次启动，正在拉取今日新闻...")
-			if err := f.FetchAndStore(today); err != nil {
-				log.Printf("首次拉取新闻失败: %v", err)
-			}
-		} else {
-			log.Println("✓ 今日新闻已存在，跳过首次拉取")
-		}
+// fetchResult is the outcome of fetchSource.
+type fetchResult struct {
+	source FeedSource
+	FeedResult
+	skipped  bool // breaker open, feed not contacted
+	err      error
+	duration time.Duration
+}
 
-		ticker := time.NewTicker(interval)
-		defer ticker.Stop()
-
-		for {
-			select {
-			case <-ticker.C:
-				date := time.Now().Format("2006-01-02")
-				log.Printf("📡 定时刷新新闻 (%s)...", date)
-				if err := f.FetchAndStore(date); err != nil {
-					log.Printf("定时拉取新闻失败: %v", err)
-				}
-			case <-f.stopCh:
-				log.Println("新闻调度器已停止")
-				return
-			}
+// record converts a result to its fetch_run_sources row.
+func (r fetchResult) record(runID int64) model.FetchRunSource {
+	rec := model.FetchRunSource{
+		RunID:      runID,
+		SourceName: r.source.Name,
+		FeedURL:    r.source.URL,
+		HTTPStatus: r.StatusCode,
+		Items:      r.Items,
+		Kept:       len(r.Articles),
+		DurationMS: r.duration.Milliseconds(),
+	}
+	switch {
+	case r.skipped:
+		rec.Status = "skipped"
+	case r.err != nil:
+		rec.Status = "error"
+		rec.Error = r.err.Error()
+	case r.NotModified:
+		rec.Status = "not_modified"
+	default:
+		rec.Status = "ok"
+	}
+	return rec
+}
+
+// fetchSource fetches one feed honouring its circuit breaker, retries transient
+// failures, and persists the new cache and breaker state. Items are filtered
+// with the keyword dictionaries kw.
+func (f *Fetcher) fetchSource(ctx context.Context, src FeedSource, kw Keywords, opts FetchOptions) fetchResult {
+	breaker, err := f.db.GetFeedBreaker(src.URL)
+	if err != nil {
+		log.Printf("读取 %s 熔断状态失败: %v", src.Name, err)
+	}
+	switch BreakerStatus(breaker, opts, time.Now()) {
+	case BreakerOpen:
+		return fetchResult{source: src, skipped: true}
+	case BreakerHalfOpen:
+		log.Printf("🔍 %s 熔断冷却结束，尝试探测", src.Name)
+	}
+
+	start := time.Now()
+	cache, err := f.db.GetFeedCache(src.URL)
+	if err != nil {
+		log.Printf("读取 %s 缓存失败: %v", src.Name, err)
+	}
+	res, err := fetchWithRetry(ctx, src, cache, kw, opts)
+	if err == nil && res.Cache != nil {
+		if err := f.db.SaveFeedCache(*res.Cache); err != nil {
+			log.Printf("保存 %s 缓存失败: %v", src.Name, err)
 		}
-	}()
+	}
+	f.recordOutcome(src, breaker, err, opts)
+	return fetchResult{source: src, FeedResult: res, err: err, duration: time.Since(start)}
 }
 
 // Stop gracefully shuts down the scheduler.
@@ -150,12 +333,57 @@ func (f *Fetcher) Stop() {
 // rawToNews converts a RawArticle to a model.News for database storage.
 func rawToNews(a RawArticle, category, date string, rank int) model.News {
 	return model.News{
-		Title:       a.Title,
-		Summary:     a.Summary,
-		SourceURL:   a.SourceURL,
-		SourceName:  a.SourceName,
-		Category:    category,
-		PublishDate: date,
-		Rank:        rank,
+		Title:        a.Title,
+		Summary:      a.Summary,
+		SourceURL:    a.SourceURL,
+		CanonicalURL: articleKey(a),
+		SourceName:   a.SourceName,
+		PublishedAt:  a.PublishDate,
+		Category:     category,
+		PublishDate:  date,
+		Rank:         rank,
+		Score:        a.Score,
+		Status:       model.NewsActive,
+		Tags:         a.Tags,
+		Entities:     a.Entities,
+	}
+}
+
+// toCandidates converts ranked articles to candidates; the first topN get a rank.
+func toCandidates(runID int64, ranked []RawArticle, category, date string, topN int) []model.Candidate {
+	cands := make([]model.Candidate, len(ranked))
+	for i, a := range ranked {
+		rank := 0
+		if i < topN {
+			rank = i + 1
+		}
+		cands[i] = model.Candidate{
+			News:         rawToNews(a, category, date, rank),
+			RunID:        runID,
+			Position:     i + 1,
+			Timeliness:   a.Components.Timeliness,
+			Relevance:    a.Components.Relevance,
+			SourceWeight: a.Components.SourceWeight,
+			Buzz:         a.Components.Buzz,
+
+			HoursAgo:      a.Components.HoursAgo,
+			Keywords:      a.Components.Keywords,
+			Hits:          a.Components.Hits,
+			WeightDefault: a.Components.WeightDefault,
+			Sources:       a.Components.Sources,
+		}
+		for _, o := range a.Also {
+			cands[i].Members = append(cands[i].Members, rawToNews(o, category, date, 0))
+		}
+	}
+	return cands
+}
+
+// articleKey identifies an article across refreshes: its canonical URL, or its
+// normalised title when the feed gives no link.
+func articleKey(a RawArticle) string {
+	if u := urlnorm.Canonical(a.SourceURL); u != "" {
+		return u
 	}
+	return "title:" + normalizeTitle(a.Title)
 }
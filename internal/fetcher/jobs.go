@@ -0,0 +1,246 @@
+package fetcher
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"top-ai-news/internal/model"
+)
+
+// Job states.
+const (
+	JobQueued    = "queued"
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+const (
+	jobQueueSize = 16
+	jobHistory   = 100 // finished jobs kept in memory for status queries
+)
+
+// ErrQueueFull is returned by Enqueue when too many jobs are waiting.
+var ErrQueueFull = errors.New("fetch queue is full")
+
+// Job is an asynchronous fetch run. Its status is guarded by mu; use Status
+// for a consistent snapshot.
+type Job struct {
+	req    runRequest
+	done   chan struct{} // closed when the job finishes
+	mu     sync.Mutex
+	status JobStatus
+}
+
+// JobStatus is a point-in-time view of a Job.
+type JobStatus struct {
+	ID         string      `json:"id"`
+	Date       string      `json:"date"` // a single date, or "from..to" for a backfill
+	Trigger    Trigger     `json:"trigger"`
+	Force      bool        `json:"force,omitempty"`
+	Status     string      `json:"status"`
+	RunID      int64       `json:"run_id,omitempty"`
+	Stored     int         `json:"stored"`
+	Error      string      `json:"error,omitempty"`
+	Sources    []JobSource `json:"sources"`
+	Done       int         `json:"sources_done"`
+	CreatedAt  time.Time   `json:"created_at"`
+	StartedAt  *time.Time  `json:"started_at,omitempty"`
+	FinishedAt *time.Time  `json:"finished_at,omitempty"`
+}
+
+// JobSource is the progress of a single feed within a job.
+type JobSource struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // "pending", or a fetch_run_sources status once done
+	Kept   int    `json:"kept"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Status returns a snapshot of the job.
+func (j *Job) Status() JobStatus {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	s := j.status
+	s.Sources = append([]JobSource(nil), j.status.Sources...)
+	return s
+}
+
+// Done returns a channel that is closed when the job has finished.
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+func (j *Job) finished() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.status.Status == JobSucceeded || j.status.Status == JobFailed
+}
+
+// begin marks the job running and lists its sources as pending.
+func (j *Job) begin(runID int64, feeds []FeedSource) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	now := time.Now()
+	j.status.Status = JobRunning
+	j.status.RunID = runID
+	j.status.StartedAt = &now
+	j.status.Sources = make([]JobSource, len(feeds))
+	for i, fs := range feeds {
+		j.status.Sources[i] = JobSource{Name: fs.Name, Status: "pending"}
+	}
+}
+
+// sourceDone records the outcome of one feed.
+func (j *Job) sourceDone(rec model.FetchRunSource) {
+	if j == nil {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for i := range j.status.Sources {
+		if j.status.Sources[i].Name == rec.SourceName {
+			j.status.Sources[i] = JobSource{Name: rec.SourceName, Status: rec.Status, Kept: rec.Kept, Error: rec.Error}
+			j.status.Done++
+			return
+		}
+	}
+}
+
+func (j *Job) finish(stored int, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	defer close(j.done)
+	now := time.Now()
+	j.status.FinishedAt = &now
+	j.status.Stored = stored
+	if err != nil {
+		j.status.Status = JobFailed
+		j.status.Error = err.Error()
+	} else {
+		j.status.Status = JobSucceeded
+	}
+}
+
+// jobQueue serialises fetch jobs through a single worker.
+type jobQueue struct {
+	once   sync.Once
+	mu     sync.Mutex
+	queue  chan *Job
+	byID   map[string]*Job
+	active map[string]*Job // queued or running job per runRequest key
+	order  []string        // job IDs, oldest first
+}
+
+// Enqueue schedules a fetch run for date and returns immediately. If a job for
+// the same date is already queued or running, that job is returned instead and
+// coalesced is true. A locked edition is only rewritten when force is set.
+func (f *Fetcher) Enqueue(date string, trigger Trigger, force bool) (job *Job, coalesced bool, err error) {
+	return f.enqueue(runRequest{dates: []string{date}, trigger: trigger, force: force})
+}
+
+// EnqueueBackfill schedules a Backfill of from..to, coalescing with an
+// identical backfill already in progress.
+func (f *Fetcher) EnqueueBackfill(from, to string, trigger Trigger, force bool) (job *Job, coalesced bool, err error) {
+	dates, err := DateRange(from, to)
+	if err != nil {
+		return nil, false, err
+	}
+	return f.enqueue(runRequest{dates: dates, trigger: trigger, force: force})
+}
+
+func (f *Fetcher) enqueue(req runRequest) (*Job, bool, error) {
+	q := &f.jobs
+	q.once.Do(func() {
+		q.queue = make(chan *Job, jobQueueSize)
+		q.byID = make(map[string]*Job)
+		q.active = make(map[string]*Job)
+		go f.runJobs()
+	})
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := req.key()
+	if j, ok := q.active[key]; ok {
+		return j, true, nil
+	}
+
+	j := &Job{req: req, done: make(chan struct{}), status: JobStatus{
+		ID:        newJobID(),
+		Date:      req.label(),
+		Trigger:   req.trigger,
+		Force:     req.force,
+		Status:    JobQueued,
+		Sources:   []JobSource{},
+		CreatedAt: time.Now(),
+	}}
+	select {
+	case q.queue <- j:
+	default:
+		return nil, false, ErrQueueFull
+	}
+	q.byID[j.status.ID] = j
+	q.active[key] = j
+	q.order = append(q.order, j.status.ID)
+	q.prune()
+	return j, false, nil
+}
+
+// Job looks up a job by ID.
+func (f *Fetcher) Job(id string) (*Job, bool) {
+	q := &f.jobs
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	j, ok := q.byID[id]
+	return j, ok
+}
+
+// runJobs is the queue worker; it exits when the fetcher is stopped.
+func (f *Fetcher) runJobs() {
+	q := &f.jobs
+	for {
+		select {
+		case j := <-q.queue:
+			st := j.Status()
+			log.Printf("📡 开始抓取任务 %s (%s, %s)", st.ID, st.Date, st.Trigger)
+			stored, err := f.run(j.req, j)
+			if err != nil {
+				log.Printf("抓取任务 %s 失败: %v", st.ID, err)
+			}
+			j.finish(stored, err)
+
+			q.mu.Lock()
+			if key := j.req.key(); q.active[key] == j {
+				delete(q.active, key)
+			}
+			q.mu.Unlock()
+		case <-f.stopCh:
+			return
+		}
+	}
+}
+
+// prune forgets the oldest finished jobs beyond jobHistory. Caller holds q.mu.
+func (q *jobQueue) prune() {
+	for len(q.order) > jobHistory {
+		id := q.order[0]
+		if j := q.byID[id]; j != nil && !j.finished() {
+			return
+		}
+		q.order = q.order[1:]
+		delete(q.byID, id)
+	}
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,155 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newQueueFetcher returns a Fetcher whose worker is held up until the returned
+// release func is called. Its only feed refuses connections, so the queued
+// runs finish quickly once released.
+func newQueueFetcher(t *testing.T) (*Fetcher, func()) {
+	t.Helper()
+	f := newTestFetcher(t, time.UTC)
+	f.active = mustParseSources(t, `
+fetch: {retries: 0, breaker_threshold: 0}
+sources:
+  - {name: down, url: "http://127.0.0.1:1/feed", category: global}
+`)
+	t.Cleanup(f.Stop)
+	f.mu.Lock() // run takes f.mu first, so the worker blocks on its first job
+	released := false
+	release := func() {
+		if !released {
+			released = true
+			f.mu.Unlock()
+		}
+	}
+	t.Cleanup(release)
+	return f, release
+}
+
+// waitTaken waits until the worker has taken every job off the queue.
+func waitTaken(t *testing.T, f *Fetcher) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(f.jobs.queue) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the worker did not take the first job")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestEnqueueCoalesces(t *testing.T) {
+	f, release := newQueueFetcher(t)
+
+	first, coalesced, err := f.Enqueue("2026-02-16", TriggerAPI, false)
+	if err != nil || coalesced {
+		t.Fatalf("first enqueue: coalesced %v, %v", coalesced, err)
+	}
+	waitTaken(t, f)
+
+	// The same request, queued or running, shares the job.
+	again, coalesced, err := f.Enqueue("2026-02-16", TriggerScheduler, false)
+	if err != nil || !coalesced || again != first {
+		t.Errorf("same date: job %p coalesced %v (%v), want job %p coalesced", again, coalesced, err, first)
+	}
+	forced, coalesced, err := f.Enqueue("2026-02-16", TriggerAPI, true)
+	if err != nil || coalesced || forced == first {
+		t.Errorf("forced run of the same date: coalesced %v (%v), want a job of its own", coalesced, err)
+	}
+	other, coalesced, err := f.Enqueue("2026-02-17", TriggerAPI, false)
+	if err != nil || coalesced || other == first {
+		t.Errorf("other date: coalesced %v (%v), want a job of its own", coalesced, err)
+	}
+	backfill, _, err := f.EnqueueBackfill("2026-02-10", "2026-02-12", TriggerAPI, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if j, coalesced, err := f.EnqueueBackfill("2026-02-10", "2026-02-12", TriggerCLI, false); err != nil || !coalesced || j != backfill {
+		t.Errorf("same backfill: coalesced %v (%v), want the queued job", coalesced, err)
+	}
+	if j, ok := f.Job(first.Status().ID); !ok || j != first {
+		t.Error("Job does not find the job by ID")
+	}
+
+	// Once a job has finished, the same request starts a new one.
+	release()
+	for _, j := range []*Job{first, forced, other, backfill} {
+		select {
+		case <-j.Done():
+		case <-time.After(10 * time.Second):
+			t.Fatalf("job %s did not finish", j.Status().Date)
+		}
+	}
+	next, coalesced, err := f.Enqueue("2026-02-16", TriggerAPI, false)
+	if err != nil || coalesced || next == first {
+		t.Errorf("after the job finished: coalesced %v (%v), want a new job", coalesced, err)
+	}
+	<-next.Done()
+}
+
+func TestEnqueueQueueFull(t *testing.T) {
+	f, release := newQueueFetcher(t)
+
+	var jobs []*Job
+	enqueue := func(i int) (*Job, error) {
+		j, _, err := f.Enqueue(fmt.Sprintf("2026-01-%02d", i+1), TriggerAPI, false)
+		if j != nil {
+			jobs = append(jobs, j)
+		}
+		return j, err
+	}
+	if _, err := enqueue(0); err != nil {
+		t.Fatal(err)
+	}
+	waitTaken(t, f)
+	for i := 1; i <= jobQueueSize; i++ {
+		if _, err := enqueue(i); err != nil {
+			t.Fatalf("job %d: %v", i, err)
+		}
+	}
+	if j, err := enqueue(jobQueueSize + 1); !errors.Is(err, ErrQueueFull) || j != nil {
+		t.Errorf("enqueue on a full queue: %v, want ErrQueueFull", err)
+	}
+	// A request that is already queued is still answered.
+	if _, coalesced, err := f.Enqueue("2026-01-02", TriggerAPI, false); err != nil || !coalesced {
+		t.Errorf("queued request on a full queue: coalesced %v, %v", coalesced, err)
+	}
+
+	release()
+	for _, j := range jobs {
+		<-j.Done()
+	}
+}
+
+func TestJobQueuePrune(t *testing.T) {
+	q := jobQueue{byID: make(map[string]*Job)}
+	n := 0
+	add := func(status string) {
+		id := fmt.Sprintf("job%03d", n)
+		n++
+		q.byID[id] = &Job{status: JobStatus{ID: id, Status: status}}
+		q.order = append(q.order, id)
+	}
+	for range jobHistory + 5 {
+		add(JobSucceeded)
+	}
+	q.prune()
+	if len(q.order) != jobHistory || len(q.byID) != jobHistory || q.order[0] != "job005" {
+		t.Errorf("after prune: %d jobs from %s, want the newest %d", len(q.order), q.order[0], jobHistory)
+	}
+
+	// An unfinished job is never forgotten, nor is anything after it.
+	q.byID[q.order[0]].status.Status = JobRunning
+	for range 3 {
+		add(JobFailed)
+	}
+	q.prune()
+	if len(q.order) != jobHistory+3 || len(q.byID) != jobHistory+3 || q.order[0] != "job005" {
+		t.Errorf("with the oldest job running: %d jobs from %s, want all %d kept", len(q.order), q.order[0], jobHistory+3)
+	}
+}
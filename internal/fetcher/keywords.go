@@ -0,0 +1,268 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+// KeywordTerm is one entry of a keyword dictionary. A plain term matches
+// case-insensitively; if it starts or ends with a Latin letter or digit it
+// must stand as a whole word there, so "ai" does not match "said". A trailing
+// "*" allows any word ending ("launch*" matches "launches"). Terms in scripts
+// without spaces, such as Chinese, match anywhere.
+type KeywordTerm struct {
+	Term   string  `yaml:"term" json:"term"`
+	Weight float64 `yaml:"weight" json:"weight"`                   // defaults to 1
+	Regex  bool    `yaml:"regex,omitempty" json:"regex,omitempty"` // Term is a regular expression, matched case-insensitively
+
+	re *regexp.Regexp
+}
+
+// UnmarshalYAML accepts a bare string as a term of weight 1.
+func (t *KeywordTerm) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*t = KeywordTerm{Term: value.Value, Weight: 1}
+		return nil
+	}
+	type plain KeywordTerm
+	p := plain{Weight: 1}
+	if err := decodeStrict(value, &p); err != nil {
+		return err
+	}
+	*t = KeywordTerm(p)
+	return nil
+}
+
+// compile prepares the term for matching.
+func (t *KeywordTerm) compile() error {
+	if strings.TrimSpace(t.Term) == "" {
+		return errors.New("empty term")
+	}
+	if t.Weight < 0 {
+		return fmt.Errorf("%q: weight must not be negative", t.Term)
+	}
+	if !t.Regex {
+		t.Term = strings.ToLower(t.Term)
+		return nil
+	}
+	re, err := regexp.Compile("(?i)" + t.Term)
+	if err != nil {
+		return fmt.Errorf("%q: %v", t.Term, err)
+	}
+	t.re = re
+	return nil
+}
+
+// matches reports whether the term occurs in text, which must be lower case.
+func (t *KeywordTerm) matches(text string) bool {
+	if t.Regex {
+		return t.re != nil && t.re.MatchString(text)
+	}
+	term, prefix := strings.CutSuffix(t.Term, "*")
+	return containsWord(text, term, prefix)
+}
+
+// containsWord finds term in text, requiring a word boundary on each side of
+// term that starts or ends with a word rune. With prefix the end is open.
+func containsWord(text, term string, prefix bool) bool {
+	if term == "" {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	checkStart, checkEnd := isWordRune(first), isWordRune(last) && !prefix
+
+	for i := 0; i < len(text); {
+		j := strings.Index(text[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (!checkStart || !isWordRune(before)) && (!checkEnd || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		i = start + size
+	}
+	return false
+}
+
+// isWordRune reports whether r belongs to a word in a script that separates
+// words with spaces.
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsDigit(r) ||
+		unicode.In(r, unicode.Latin, unicode.Greek, unicode.Cyrillic)
+}
+
+// KeywordList is a keyword dictionary.
+type KeywordList []KeywordTerm
+
+// Match returns the terms found in text and the sum of their weights.
+func (l KeywordList) Match(text string) (hits []string, weight float64) {
+	text = strings.ToLower(text)
+	for i := range l {
+		if l[i].matches(text) {
+			hits = append(hits, l[i].Term)
+			weight += l[i].Weight
+		}
+	}
+	return hits, weight
+}
+
+// Any reports whether any term occurs in text.
+func (l KeywordList) Any(text string) bool {
+	text = strings.ToLower(text)
+	for i := range l {
+		if l[i].matches(text) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l KeywordList) compile(where string) error {
+	var errs []error
+	for i := range l {
+		if err := l[i].compile(); err != nil {
+			errs = append(errs, fmt.Errorf("%s[%d]: %w", where, i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Keywords are the keyword dictionaries of a category.
+type Keywords struct {
+	Filter    KeywordList `yaml:"filter" json:"filter"`         // items of sources that are not ai_only must match one
+	HighValue KeywordList `yaml:"high_value" json:"high_value"` // add their weights to the relevance hits
+	Exclude   KeywordList `yaml:"exclude" json:"exclude"`       // items matching one are dropped, from any source
+}
+
+// Admit reports whether an item with text passes the keyword filters of a
+// source; aiOnly sources skip the Filter dictionary but not Exclude.
+func (k Keywords) Admit(text string, aiOnly bool) bool {
+	if k.Exclude.Any(text) {
+		return false
+	}
+	return aiOnly || k.Filter.Any(text)
+}
+
+// KeywordOptions holds the keyword dictionaries of each category.
+type KeywordOptions map[string]Keywords
+
+// DefaultKeywordOptions returns the compiled-in dictionaries of the default
+// categories.
+func DefaultKeywordOptions() KeywordOptions {
+	return KeywordOptions{}.withDefaults(DefaultCategories())
+}
+
+// defaultKeywords returns the compiled-in dictionaries of category. The
+// domestic and global categories have their own; any other category gets
+// both combined, so its feeds are filtered for AI news in either language.
+// It panics if a compiled-in term does not compile.
+func defaultKeywords(category string) Keywords {
+	var k Keywords
+	switch category {
+	case "domestic":
+		k = Keywords{Filter: terms(domesticKeywords), HighValue: terms(highValueDomestic)}
+	case "global":
+		k = Keywords{Filter: terms(globalKeywords), HighValue: terms(highValueGlobal)}
+	default:
+		k = Keywords{
+			Filter:    terms(append(append([]string(nil), domesticKeywords...), globalKeywords...)),
+			HighValue: terms(append(append([]string(nil), highValueDomestic...), highValueGlobal...)),
+		}
+	}
+	if err := errors.Join(k.Filter.compile(category+".filter"), k.HighValue.compile(category+".high_value")); err != nil {
+		panic("default keywords: " + err.Error())
+	}
+	return k
+}
+
+// terms turns a plain word list into a dictionary of weight 1 terms.
+func terms(words []string) KeywordList {
+	l := make(KeywordList, len(words))
+	for i, w := range words {
+		l[i] = KeywordTerm{Term: w, Weight: 1}
+	}
+	return l
+}
+
+// UnmarshalYAML starts every category it lists from the compiled-in
+// dictionaries; a category only needs to list the dictionaries it replaces.
+// Categories it does not list get theirs from withDefaults.
+func (o *KeywordOptions) UnmarshalYAML(value *yaml.Node) error {
+	var raw map[string]map[string]yaml.Node
+	if err := decodeStrict(value, &raw); err != nil {
+		return err
+	}
+
+	opts := make(KeywordOptions, len(raw))
+	for category, lists := range raw {
+		k := defaultKeywords(category)
+		for name, node := range lists {
+			var dst *KeywordList
+			switch name {
+			case "filter":
+				dst = &k.Filter
+			case "high_value":
+				dst = &k.HighValue
+			case "exclude":
+				dst = &k.Exclude
+			default:
+				return fmt.Errorf("keywords.%s: unknown dictionary %q (want filter, high_value or exclude)", category, name)
+			}
+			*dst = nil
+			if err := decodeStrict(&node, dst); err != nil {
+				return fmt.Errorf("keywords.%s.%s: %w", category, name, err)
+			}
+		}
+		opts[category] = k
+	}
+	*o = opts
+	return nil
+}
+
+// For returns the dictionaries of category.
+func (o KeywordOptions) For(category string) Keywords {
+	return o[category]
+}
+
+// withDefaults returns o with the compiled-in dictionaries added for the
+// categories it does not configure.
+func (o KeywordOptions) withDefaults(categories Categories) KeywordOptions {
+	out := make(KeywordOptions, len(categories))
+	for _, c := range categories {
+		k, ok := o[c.Name]
+		if !ok {
+			k = defaultKeywords(c.Name)
+		}
+		out[c.Name] = k
+	}
+	return out
+}
+
+// compile prepares every term and reports the invalid ones and the
+// dictionaries of unknown categories.
+func (o KeywordOptions) compile(categories Categories) error {
+	var errs []error
+	for category, k := range o {
+		where := "keywords." + category
+		if !categories.Has(category) {
+			errs = append(errs, fmt.Errorf("%s: unknown category", where))
+		}
+		errs = append(errs,
+			k.Filter.compile(where+".filter"),
+			k.HighValue.compile(where+".high_value"),
+			k.Exclude.compile(where+".exclude"),
+		)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,163 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultFilterHeadlines(t *testing.T) {
+	kw := DefaultKeywordOptions()
+	tests := []struct {
+		category string
+		headline string
+		want     bool
+	}{
+		// "ai" inside other words must not count.
+		{"global", "Taiwan says it paid for chip plant upgrades, official said", false},
+		{"global", "Apple’s M4 MacBook Air review: the laptop most people should buy", false},
+		{"global", "Maine lawmakers detail plan to fix rail line", false},
+		{"global", "Nvidia’s new AI chips are sold out through next year", true},
+		{"global", "AI-generated images flood stock photo sites", true},
+		{"global", "OpenAI announces GPT-4o, a new flagship model", true},
+		{"global", "Google’s Gemini 1.5 Pro can now hear audio", true},
+		{"global", "Valve’s Steam Deck gets a brighter OLED screen", false},
+		{"global", "Startups race to build AI agents for office work", true},
+		{"global", "New GPT5 benchmark leaks", true},
+		{"domestic", "DeepSeek发布新一代推理大模型", true},
+		{"domestic", "国产AI芯片公司完成新一轮融资", true},
+		{"domestic", "小米SU7 Ultra 正式发布", false},
+		{"domestic", "智能体成为今年创业热点", true},
+		{"domestic", "36氪首发｜某消费品牌完成亿元融资", false},
+	}
+	for _, tt := range tests {
+		got := kw.For(tt.category).Admit(tt.headline, false)
+		if got != tt.want {
+			t.Errorf("%s %q: admitted = %v, want %v", tt.category, tt.headline, got, tt.want)
+		}
+	}
+}
+
+func TestDefaultKeywordsCompile(t *testing.T) {
+	// defaultKeywords panics on a term that does not compile.
+	for _, category := range []string{"domestic", "global", "other"} {
+		k := defaultKeywords(category)
+		if len(k.Filter) == 0 || len(k.HighValue) == 0 {
+			t.Errorf("%s: %d filter and %d high-value terms", category, len(k.Filter), len(k.HighValue))
+		}
+	}
+}
+
+func TestDefaultHighValueHeadlines(t *testing.T) {
+	kw := DefaultKeywordOptions()
+	tests := []struct {
+		category string
+		headline string
+		want     []string
+	}{
+		{"global", "OpenAI launches GPT-4o for free ChatGPT users", []string{"launch*", "gpt*", "openai"}},
+		{"global", "Anthropic raises $2.75 billion in new funding", []string{"anthropic", "funding", "billion"}},
+		{"global", "EU passes landmark AI regulation", []string{"regulation"}},
+		{"global", "Googlers say the new search feature is rushed", nil},
+		{"domestic", "阿里通义千问发布开源大模型", []string{"发布", "开源", "大模型", "通义"}},
+		{"domestic", "首个国产GPT-4级别模型上线", []string{"首个", "gpt*", "上线"}},
+	}
+	for _, tt := range tests {
+		got, hits := kw.For(tt.category).HighValue.Match(tt.headline)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: matched %v, want %v", tt.headline, got, tt.want)
+		}
+		if hits != float64(len(tt.want)) {
+			t.Errorf("%q: hits = %v, want %d", tt.headline, hits, len(tt.want))
+		}
+	}
+}
+
+func TestContainsWord(t *testing.T) {
+	tests := []struct {
+		text, term string
+		prefix     bool
+		want       bool
+	}{
+		{"said", "ai", false, false},
+		{"ai said", "ai", false, true},
+		{"said ai", "ai", false, true},
+		{"ai芯片", "ai", false, true},
+		{"(ai)", "ai", false, true},
+		{"launches", "launch", false, false},
+		{"launches", "launch", true, true},
+		{"relaunches", "launch", true, false},
+		{"大模型发布", "发布", false, true},
+		{"open-source", "open source", false, false},
+		{"", "ai", false, false},
+	}
+	for _, tt := range tests {
+		if got := containsWord(tt.text, tt.term, tt.prefix); got != tt.want {
+			t.Errorf("containsWord(%q, %q, %v) = %v, want %v", tt.text, tt.term, tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestKeywordsConfig(t *testing.T) {
+	set := mustParseSources(t, `
+keywords:
+  global:
+    high_value:
+      - openai
+      - {term: launch*, weight: 2}
+      - {term: 'gpt-?\d', regex: true, weight: 0.5}
+    exclude:
+      - sponsored
+      - {term: '\bdeals?\b', regex: true}`+oneSource)
+	global := set.Keywords.For("global")
+
+	got, hits := global.HighValue.Match("OpenAI launches GPT-5 to all users")
+	if want := []string{"openai", "launch*", `gpt-?\d`}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matched %v, want %v", got, want)
+	}
+	if hits != 3.5 {
+		t.Errorf("hits = %v, want 3.5", hits)
+	}
+
+	excluded := []string{
+		"Sponsored: the AI tool every marketer needs",
+		"The best AI gadget deals this week",
+		"Deal: save $50 on a ChatGPT Plus gift card",
+	}
+	for _, h := range excluded {
+		if global.Admit(h, true) {
+			t.Errorf("%q: admitted despite a negative keyword", h)
+		}
+	}
+	if !global.Admit("Microsoft ideals for AI safety", true) {
+		t.Error("regex word boundary matched inside a word")
+	}
+
+	// Dictionaries not listed keep their defaults.
+	if !global.Admit("OpenAI ships a new model", false) {
+		t.Error("default global filter dictionary was lost")
+	}
+	if len(set.Keywords.For("domestic").HighValue) == 0 {
+		t.Error("default domestic dictionaries were lost")
+	}
+}
+
+func TestKeywordsConfigErrors(t *testing.T) {
+	wantSourcesErrors(t, `
+keywords:
+  global:
+    exclude:
+      - {term: '(unclosed', regex: true}
+      - {term: spam, weight: -1}
+  research:
+    filter: [paper]`+oneSource,
+		"keywords.global.exclude[0]", "keywords.global.exclude[1]", "keywords.research: unknown category")
+}
+
+func TestKeywordsConfigUnknownFields(t *testing.T) {
+	wantSourcesErrors(t, `
+keywords:
+  global:
+    high_value:
+      - {term: x, wieght: 3}`+oneSource,
+		`keywords.global.high_value: line 5: unknown field "wieght" (want one of regex, term, weight)`)
+}
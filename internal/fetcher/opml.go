@@ -0,0 +1,363 @@
+package fetcher
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OPML 2.0 document. Weight and AIOnly have no OPML equivalent, so they are
+// carried in the custom "weight" and "aiOnly" outline attributes.
+type opmlDoc struct {
+	XMLName xml.Name `xml:"opml"`
+	Version string   `xml:"version,attr"`
+	Head    opmlHead `xml:"head"`
+	Body    opmlBody `xml:"body"`
+}
+
+type opmlHead struct {
+	Title       string `xml:"title,omitempty"`
+	DateCreated string `xml:"dateCreated,omitempty"`
+}
+
+type opmlBody struct {
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+type opmlOutline struct {
+	Text     string        `xml:"text,attr"`
+	Title    string        `xml:"title,attr,omitempty"`
+	Type     string        `xml:"type,attr,omitempty"`
+	XMLURL   string        `xml:"xmlUrl,attr,omitempty"`
+	HTMLURL  string        `xml:"htmlUrl,attr,omitempty"`
+	Weight   string        `xml:"weight,attr,omitempty"`
+	AIOnly   string        `xml:"aiOnly,attr,omitempty"`
+	Outlines []opmlOutline `xml:"outline"`
+}
+
+// ExportOPML writes feeds as an OPML 2.0 document with one folder per category.
+func ExportOPML(w io.Writer, feeds []FeedSource) error {
+	doc := opmlDoc{
+		Version: "2.0",
+		Head: opmlHead{
+			Title:       "Top AI News sources",
+			DateCreated: time.Now().Format(time.RFC1123Z),
+		},
+	}
+
+	folders := make(map[string]int)
+	for _, fs := range feeds {
+		i, ok := folders[fs.Category]
+		if !ok {
+			i = len(doc.Body.Outlines)
+			folders[fs.Category] = i
+			doc.Body.Outlines = append(doc.Body.Outlines, opmlOutline{Text: fs.Category, Title: fs.Category})
+		}
+		doc.Body.Outlines[i].Outlines = append(doc.Body.Outlines[i].Outlines, opmlOutline{
+			Text:   fs.Name,
+			Title:  fs.Name,
+			Type:   "rss",
+			XMLURL: fs.URL,
+			Weight: strconv.FormatFloat(fs.Weight, 'f', -1, 64),
+			AIOnly: strconv.FormatBool(fs.AIOnly),
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode opml: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// ParseOPML reads an OPML document and maps every feed outline to a source
+// entry. The category is taken from the enclosing folder outline, named after
+// one of categories or its label; feeds outside a known category folder get
+// defaultCategory when it is set.
+func ParseOPML(r io.Reader, defaultCategory string, categories Categories) ([]SourceEntry, error) {
+	var doc opmlDoc
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("parse opml: %w", err)
+	}
+
+	var entries []SourceEntry
+	var walk func(outlines []opmlOutline, folder string) error
+	walk = func(outlines []opmlOutline, folder string) error {
+		for _, o := range outlines {
+			if o.XMLURL == "" {
+				name := o.Text
+				if name == "" {
+					name = o.Title
+				}
+				if err := walk(o.Outlines, strings.TrimSpace(name)); err != nil {
+					return err
+				}
+				continue
+			}
+
+			e := SourceEntry{
+				Name:     strings.TrimSpace(o.Title),
+				URL:      strings.TrimSpace(o.XMLURL),
+				Category: outlineCategory(folder, defaultCategory, categories),
+			}
+			if e.Name == "" {
+				e.Name = strings.TrimSpace(o.Text)
+			}
+			if o.Weight != "" {
+				w, err := strconv.ParseFloat(o.Weight, 64)
+				if err != nil {
+					return fmt.Errorf("outline %q: bad weight %q", e.Name, o.Weight)
+				}
+				e.Weight = &w
+			}
+			if o.AIOnly != "" {
+				v, err := strconv.ParseBool(o.AIOnly)
+				if err != nil {
+					return fmt.Errorf("outline %q: bad aiOnly %q", e.Name, o.AIOnly)
+				}
+				e.AIOnly = v
+			}
+			entries = append(entries, e)
+		}
+		return nil
+	}
+	if err := walk(doc.Body.Outlines, ""); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// outlineCategory maps an OPML folder name to a source category. Unknown
+// folders fall back to def, or are kept as-is so validation can report them.
+func outlineCategory(folder, def string, categories Categories) string {
+	for _, c := range categories {
+		if strings.EqualFold(folder, c.Name) || folder == c.Label {
+			return c.Name
+		}
+	}
+	if def != "" {
+		return def
+	}
+	return folder
+}
+
+// ImportOPML merges the feeds from an OPML document into the sources file at
+// path. Feeds whose URL is already present are skipped; a name clash is
+// resolved by appending the feed's domain. A missing file is first created from
+// the compiled-in feeds. The merged file is validated before it is written.
+// Only the new entries are appended to the sources list; the rest of the file,
+// comments included, is kept as written. It returns the number of feeds added.
+func ImportOPML(path string, r io.Reader, defaultCategory string) (int, error) {
+	if err := ensureSourcesFile(path); err != nil {
+		return 0, err
+	}
+	file, err := readSourcesFile(path)
+	if err != nil {
+		return 0, err
+	}
+	imported, err := ParseOPML(r, defaultCategory, file.categories())
+	if err != nil {
+		return 0, err
+	}
+
+	urls := make(map[string]bool)
+	names := make(map[string]bool)
+	for _, e := range file.Sources {
+		urls[e.URL] = true
+		names[e.Name] = true
+	}
+
+	var added []SourceEntry
+	for _, e := range imported {
+		if urls[e.URL] {
+			continue
+		}
+		if names[e.Name] {
+			e.Name = fmt.Sprintf("%s (%s)", e.Name, extractDomainFromURL(e.URL))
+		}
+		urls[e.URL] = true
+		names[e.Name] = true
+		file.Sources = append(file.Sources, e)
+		added = append(added, e)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+
+	if err := ValidateSources(file.Sources, file.categories()); err != nil {
+		return 0, err
+	}
+	data, err := appendSources(path, added)
+	if err != nil {
+		return 0, err
+	}
+	if err := writeSourcesFile(path, data); err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
+
+// appendSources returns the sources file at path with entries appended to its
+// sources list. The new entries are inserted as text after the last entry of
+// a block list, so the rest of the file is kept byte for byte: comments stay
+// and the defaults filled in by decoding are not written into it. A file whose
+// list is in flow style (or JSON) is edited as a YAML node tree instead.
+func appendSources(path string, entries []SourceEntry) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sources file: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: parse sources: %w", path, err)
+	}
+	var root *yaml.Node
+	if len(doc.Content) > 0 {
+		root = doc.Content[0]
+		if root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("%s: sources file is not a mapping", path)
+		}
+	}
+	var key, list *yaml.Node
+	for i := 0; root != nil && i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value == "sources" {
+			key, list = root.Content[i], root.Content[i+1]
+			break
+		}
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(entries); err != nil {
+		return nil, fmt.Errorf("encode sources: %w", err)
+	}
+	enc.Close()
+	items := buf.String()
+
+	lines := strings.SplitAfter(string(data), "\n")
+	if n := len(lines); n > 0 && lines[n-1] == "" {
+		lines = lines[:n-1]
+	}
+	if n := len(lines); n > 0 && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	insert := func(after int, text string) []byte {
+		out := slices.Concat(lines[:after], []string{text}, lines[after:])
+		return []byte(strings.Join(out, ""))
+	}
+
+	switch {
+	case list == nil:
+		return insert(len(lines), "sources:\n"+indentLines(items, "  ")), nil
+	case list.Kind == yaml.ScalarNode && list.Tag == "!!null" && list.Line == key.Line:
+		return insert(key.Line, indentLines(items, "  ")), nil
+	case list.Kind == yaml.SequenceNode && list.Style&yaml.FlowStyle == 0 && len(list.Content) > 0:
+		first := list.Content[0]
+		dash := strings.LastIndex(lines[first.Line-1][:first.Column-1], "-")
+		if dash < 0 {
+			return nil, fmt.Errorf("%s: cannot find the sources list", path)
+		}
+		// Take in the continuation lines of the last entry, but not the blank
+		// lines that separate it from what follows.
+		end := lastLine(list.Content[len(list.Content)-1])
+		for i := end; i < len(lines); i++ {
+			if strings.TrimSpace(lines[i]) == "" {
+				continue
+			}
+			if indentOf(lines[i]) <= dash {
+				break
+			}
+			end = i + 1
+		}
+		return insert(end, indentLines(items, strings.Repeat(" ", dash))), nil
+	}
+
+	// Flow-style or empty list: edit the node tree.
+	if list.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("%s: sources is not a list", path)
+	}
+	for _, e := range entries {
+		var n yaml.Node
+		if err := n.Encode(e); err != nil {
+			return nil, fmt.Errorf("encode sources: %w", err)
+		}
+		if list.Style&yaml.FlowStyle != 0 {
+			n.Style = yaml.FlowStyle
+		}
+		list.Content = append(list.Content, &n)
+	}
+	buf.Reset()
+	enc = yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return nil, fmt.Errorf("encode sources: %w", err)
+	}
+	enc.Close()
+	return buf.Bytes(), nil
+}
+
+// lastLine returns the last line on which node or one of its children starts.
+func lastLine(node *yaml.Node) int {
+	line := node.Line
+	for _, c := range node.Content {
+		line = max(line, lastLine(c))
+	}
+	return line
+}
+
+// indentOf returns the number of leading spaces of line.
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// indentLines prefixes every line of text with indent.
+func indentLines(text, indent string) string {
+	lines := strings.SplitAfter(text, "\n")
+	for i, l := range lines {
+		if l != "" {
+			lines[i] = indent + l
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// ImportOPML merges an OPML document into the configured sources file and
+// reloads it.
+func (f *Fetcher) ImportOPML(r io.Reader, defaultCategory string) (int, error) {
+	if f.sourcesPath == "" {
+		return 0, fmt.Errorf("no sources file configured")
+	}
+	added, err := ImportOPML(f.sourcesPath, r, defaultCategory)
+	if err != nil || added == 0 {
+		return added, err
+	}
+	return added, f.ReloadSources()
+}
+
+// readSourcesFile reads the raw entries of a sources file, including disabled
+// ones.
+func readSourcesFile(path string) (SourcesFile, error) {
+	var file SourcesFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("read sources file: %w", err)
+	}
+	if err := decodeSourcesFile(data, &file); err != nil {
+		return file, fmt.Errorf("%s: %w", path, err)
+	}
+	return file, nil
+}
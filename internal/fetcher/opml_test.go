@@ -0,0 +1,76 @@
+package fetcher
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOPMLRoundTrip(t *testing.T) {
+	feeds := []FeedSource{
+		{Name: "A", URL: "https://a.example/rss", Category: "domestic", AIOnly: true, Weight: 0.9},
+		{Name: "B", URL: "https://b.example/feed", Category: "global", Weight: 0.35},
+		{Name: "Existing", URL: "https://c.example/rss", Category: "global", Weight: 0.7},
+	}
+	var opml bytes.Buffer
+	if err := ExportOPML(&opml, feeds); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "sources.yaml")
+	orig := `# my feeds
+sources:
+  - name: Existing # keep me
+    url: https://c.example/rss
+    category: global
+`
+	if err := os.WriteFile(path, []byte(orig), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	added, err := ImportOPML(path, bytes.NewReader(opml.Bytes()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if added != 2 {
+		t.Errorf("added %d feeds, want 2", added)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The file is appended to, not rewritten: comments stay and no defaults
+	// are written into it.
+	if !strings.HasPrefix(string(data), orig) {
+		t.Errorf("import rewrote the existing file:\n%s", data)
+	}
+	for _, section := range []string{"ranking:", "keywords:", "fetch:", "categories:"} {
+		if strings.Contains(string(data), section) {
+			t.Errorf("import wrote the %s defaults:\n%s", section, data)
+		}
+	}
+
+	file, err := readSourcesFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(file.Sources) != len(feeds) || file.Sources[0].Name != "Existing" {
+		t.Fatalf("sources %+v, want Existing followed by the imported feeds", file.Sources)
+	}
+	for i, want := range feeds[:2] {
+		got := file.Sources[i+1]
+		if got.Name != want.Name || got.URL != want.URL || got.Category != want.Category || got.AIOnly != want.AIOnly {
+			t.Errorf("source %+v, want %+v", got, want)
+		}
+		if got.Weight == nil || *got.Weight != want.Weight {
+			t.Errorf("%s: weight %v, want %v", want.Name, got.Weight, want.Weight)
+		}
+	}
+
+	added, err = ImportOPML(path, bytes.NewReader(opml.Bytes()), "")
+	if err != nil || added != 0 {
+		t.Errorf("second import added %d feeds (%v), want 0", added, err)
+	}
+}
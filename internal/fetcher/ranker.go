@@ -1,114 +1,191 @@
 package fetcher
 
 import (
-	"math"
 	"sort"
 	"strings"
 	"time"
 )
 
-// High-value keywords that boost relevance score.
+// High-value keywords that boost relevance score; the defaults of the
+// high_value dictionaries.
 var highValueDomestic = []string{
-	"发布", "开源", "突破", "首个", "领先", "gpt", "大模型", "融资",
+	"发布", "开源", "突破", "首个", "领先", "gpt*", "大模型", "融资",
 	"deepseek", "通义", "文心", "商用", "上线", "芯片",
 }
 
 var highValueGlobal = []string{
-	"release", "launch", "open source", "breakthrough", "gpt", "llm",
+	"release*", "launch*", "open source", "breakthrough*", "gpt*", "llm*",
 	"openai", "anthropic", "deepmind", "google", "meta", "nvidia",
 	"funding", "billion", "regulation", "safety",
 }
 
-// RankAndSelect scores, deduplicates, sorts, and returns the top N articles.
-func RankAndSelect(articles []RawArticle, topN int, sourceWeights map[string]float64) []RawArticle {
+// DefaultSourceScore is the source weight of feeds that do not set one, and of
+// sources the ranker has no weight for.
+const DefaultSourceScore = 0.7
+
+// ScoreComponents are the unweighted inputs of an article's score, with the
+// details needed to explain them.
+type ScoreComponents struct {
+	Timeliness    float64  `json:"timeliness"`     // exponential decay of age, [0, 1]
+	Relevance     float64  `json:"relevance"`      // high-value keyword hits, [0, 1]
+	SourceWeight  float64  `json:"source_weight"`  // configured weight of the source
+	HoursAgo      float64  `json:"hours_ago"`      // age when scored
+	Keywords      []string `json:"keywords"`       // high-value keywords that matched
+	Hits          float64  `json:"hits"`           // sum of the weights of Keywords
+	Buzz          float64  `json:"buzz"`           // coverage by other sources, [0, 1]
+	Sources       int      `json:"sources"`        // sources that covered the story, at least 1
+	WeightDefault bool     `json:"weight_default"` // source had no weight, DefaultSourceScore was used
+}
+
+// RankAndSelect scores, deduplicates, sorts, and returns the top N articles
+// with the default keywords and ranking options, keeping the top N diverse.
+// Timeliness is measured relative to now.
+func RankAndSelect(articles []RawArticle, topN int, sourceWeights map[string]float64, now time.Time) []RawArticle {
+	opts := DefaultRankingOptions()
+	articles = Rank(articles, sourceWeights, DefaultKeywordOptions(), opts, now)
+	articles = Diversify(articles, topN, opts.Diversity)
+
+	// Return top N
+	if len(articles) > topN {
+		articles = articles[:topN]
+	}
+	return articles
+}
+
+// Rank scores articles with the high-value keywords of kw and the strategy
+// and per-category parameters of opts, then clusters duplicates into stories
+// and re-scores each story's representative with its coverage. It returns
+// every candidate in score order. Timeliness is measured relative to now.
+func Rank(articles []RawArticle, sourceWeights map[string]float64, kw KeywordOptions, opts RankingOptions, now time.Time) []RawArticle {
 	if len(articles) == 0 {
 		return nil
 	}
 
-	now := time.Now()
-
 	// Score each article
 	for i := range articles {
-		articles[i].Score = computeScore(articles[i], now, sourceWeights)
+		opts.score(&articles[i], scoreComponents(articles[i], now, sourceWeights, kw))
 	}
 
-	// Sort by score descending
-	sort.Slice(articles, func(i, j int) bool {
+	// Sort by score descending; ties keep feed order so the representative
+	// of a story is deterministic
+	sort.SliceStable(articles, func(i, j int) bool {
 		return articles[i].Score > articles[j].Score
 	})
 
-	// Deduplicate by title similarity
-	articles = dedup(articles)
-
-	// Return top N
-	if len(articles) > topN {
-		articles = articles[:topN]
+	// Cluster by title and text similarity; the best-scored article of a
+	// story represents it and gains buzz from the other sources.
+	articles = dedup(articles, opts.Dedup)
+	for i := range articles {
+		c := articles[i].Components
+		c.Sources = 1 + len(articles[i].Also)
+		opts.score(&articles[i], c)
 	}
+	sort.SliceStable(articles, func(i, j int) bool {
+		return articles[i].Score > articles[j].Score
+	})
 	return articles
 }
 
-// computeScore calculates a weighted score for an article.
-// Timeliness 50% + Relevance 30% + Source Weight 20%
+// computeScore calculates an article's score with the default strategy and
+// parameters.
 func computeScore(a RawArticle, now time.Time, sourceWeights map[string]float64) float64 {
-	// 1. Timeliness (50%) — exponential decay, 12-hour half-life
+	opts := DefaultRankingOptions()
+	opts.score(&a, scoreComponents(a, now, sourceWeights, DefaultKeywordOptions()))
+	return a.Score
+}
+
+// scoreComponents collects the raw inputs of a score: age, matched high-value
+// keywords and source weight. Timeliness and Relevance are left for the Scorer.
+func scoreComponents(a RawArticle, now time.Time, sourceWeights map[string]float64, kw KeywordOptions) ScoreComponents {
 	hoursAgo := now.Sub(a.PublishDate).Hours()
 	if hoursAgo < 0 {
 		hoursAgo = 0
 	}
-	halfLife := 12.0
-	timeliness := math.Exp(-0.693 * hoursAgo / halfLife) // ln(2) ≈ 0.693
 
-	// 2. Relevance (30%) — high-value keyword hits, capped at 5
-	relevance := computeRelevance(a)
-
-	// 3. Source weight (20%)
-	sw := 0.7 // default for unknown sources
-	if w, ok := sourceWeights[a.SourceName]; ok {
-		sw = w
+	sw, ok := sourceWeights[a.SourceName]
+	if !ok {
+		sw = DefaultSourceScore
 	}
 
-	return 0.5*timeliness + 0.3*relevance + 0.2*sw
+	keywords, hits := matchedKeywords(a, kw.For(a.Category))
+	return ScoreComponents{
+		SourceWeight:  sw,
+		HoursAgo:      hoursAgo,
+		Keywords:      keywords,
+		Hits:          hits,
+		Sources:       1,
+		WeightDefault: !ok,
+	}
 }
 
-// computeRelevance counts high-value keyword matches, normalized to [0, 1].
+// computeRelevance weighs the default high-value keyword matches, normalized
+// to [0, 1].
 func computeRelevance(a RawArticle) float64 {
-	text := strings.ToLower(a.Title + " " + a.Summary)
-
-	var keywords []string
-	if a.Category == "domestic" {
-		keywords = highValueDomestic
-	} else {
-		keywords = highValueGlobal
-	}
+	_, hits := matchedKeywords(a, DefaultKeywordOptions().For(a.Category))
+	return relevanceOf(hits, DefaultScoreParams().KeywordCap)
+}
 
-	hits := 0
-	for _, kw := range keywords {
-		if strings.Contains(text, kw) {
-			hits++
-		}
-	}
-	if hits > 5 {
-		hits = 5
-	}
-	return float64(hits) / 5.0
+// matchedKeywords returns the high-value keywords of kw found in a's title or
+// summary and the sum of their weights.
+func matchedKeywords(a RawArticle, kw Keywords) ([]string, float64) {
+	return kw.HighValue.Match(a.Title + " " + a.Summary)
 }
 
-// dedup removes articles with very similar titles (keeping the higher-scored one).
-func dedup(sorted []RawArticle) []RawArticle {
+// dedup clusters articles with very similar titles and near-duplicates (see
+// DedupOptions) into stories, keeping the higher-scored article of each. The
+// versions of a story from other sources are attached to it as Also.
+func dedup(sorted []RawArticle, opts DedupOptions) []RawArticle {
 	var result []RawArticle
-	seen := make(map[string]bool)
+	var sigs []*signature
+	seen := make(map[string]int) // normalised title → index in result
 
 	for _, a := range sorted {
 		key := normalizeTitle(a.Title)
-		if seen[key] {
+		if i, ok := seen[key]; ok {
+			result[i].addCoverage(a)
+			continue
+		}
+		sig := minHash(a.Title + " " + a.Summary)
+		if i := nearDuplicateOf(sig, sigs, opts.Threshold); i >= 0 {
+			result[i].addCoverage(a)
 			continue
 		}
-		seen[key] = true
+		seen[key] = len(result)
+		a.Also = nil
 		result = append(result, a)
+		sigs = append(sigs, sig)
 	}
 	return result
 }
 
+// nearDuplicateOf returns the index of the first of kept that sig duplicates,
+// or -1.
+func nearDuplicateOf(sig *signature, kept []*signature, threshold float64) int {
+	if threshold <= 0 {
+		return -1
+	}
+	for i, k := range kept {
+		if nearDuplicate(sig, k, threshold) {
+			return i
+		}
+	}
+	return -1
+}
+
+// addCoverage records dup as coverage of a's story, once per other source.
+func (a *RawArticle) addCoverage(dup RawArticle) {
+	if dup.SourceName == a.SourceName {
+		return
+	}
+	for _, o := range a.Also {
+		if o.SourceName == dup.SourceName {
+			return
+		}
+	}
+	dup.Also = nil
+	a.Also = append(a.Also, dup)
+}
+
 // normalizeTitle creates a simplified key for dedup comparison.
 func normalizeTitle(title string) string {
 	t := strings.ToLower(title)
@@ -0,0 +1,318 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"top-ai-news/internal/model"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultStrategy is the scorer used when the sources file does not pick one.
+const DefaultStrategy = "weighted"
+
+// ScoreParams tunes a Scorer for one category.
+type ScoreParams struct {
+	Timeliness    float64 `yaml:"timeliness" json:"timeliness"`           // weight of timeliness
+	Relevance     float64 `yaml:"relevance" json:"relevance"`             // weight of keyword relevance
+	Source        float64 `yaml:"source" json:"source"`                   // weight of the source weight
+	HalfLifeHours float64 `yaml:"half_life_hours" json:"half_life_hours"` // timeliness halves every this many hours
+	KeywordCap    int     `yaml:"keyword_cap" json:"keyword_cap"`         // weighted keyword hits that give full relevance
+	Buzz          float64 `yaml:"buzz" json:"buzz"`                       // weight of coverage by other sources
+	BuzzCap       int     `yaml:"buzz_cap" json:"buzz_cap"`               // other sources that give full buzz
+}
+
+// DefaultScoreParams returns the original blend: timeliness 50%, relevance
+// 30%, source weight 20%, a 12-hour half-life and relevance capped at 5 hits,
+// plus a bonus of up to 0.15 for stories three other sources also covered.
+func DefaultScoreParams() ScoreParams {
+	return ScoreParams{Timeliness: 0.5, Relevance: 0.3, Source: 0.2, HalfLifeHours: 12, KeywordCap: 5, Buzz: 0.15, BuzzCap: 3}
+}
+
+func (p ScoreParams) validate(where string) error {
+	var errs []error
+	if p.Timeliness < 0 || p.Relevance < 0 || p.Source < 0 || p.Buzz < 0 {
+		errs = append(errs, fmt.Errorf("%s: weights must not be negative", where))
+	}
+	if p.HalfLifeHours <= 0 {
+		errs = append(errs, fmt.Errorf("%s: half_life_hours must be positive", where))
+	}
+	if p.KeywordCap < 1 {
+		errs = append(errs, fmt.Errorf("%s: keyword_cap must be at least 1", where))
+	}
+	if p.BuzzCap < 1 {
+		errs = append(errs, fmt.Errorf("%s: buzz_cap must be at least 1", where))
+	}
+	return errors.Join(errs...)
+}
+
+// Contributions split a score into what each component added to it.
+type Contributions struct {
+	Timeliness float64 `json:"timeliness"`
+	Relevance  float64 `json:"relevance"`
+	Source     float64 `json:"source"`
+	Buzz       float64 `json:"buzz"`
+}
+
+// Total is the score.
+func (c Contributions) Total() float64 {
+	return c.Timeliness + c.Relevance + c.Source + c.Buzz
+}
+
+// Scorer turns the raw inputs of a score (HoursAgo, Hits, SourceWeight and
+// Sources of ScoreComponents) into the Timeliness, Relevance and Buzz values and each
+// component's contribution to the score. A Scorer must depend on nothing else,
+// so a stored candidate pool can be re-scored with another strategy.
+type Scorer interface {
+	Score(in ScoreComponents, p ScoreParams) (ScoreComponents, Contributions)
+}
+
+var scorers = map[string]Scorer{
+	"weighted": WeightedScorer{},
+	"decay":    DecayScorer{},
+}
+
+// RegisterScorer makes a strategy selectable by name. It is meant to be called
+// from init functions.
+func RegisterScorer(name string, s Scorer) {
+	scorers[name] = s
+}
+
+// ScorerByName returns the registered strategy called name.
+func ScorerByName(name string) (Scorer, bool) {
+	s, ok := scorers[name]
+	return s, ok
+}
+
+// ScorerNames lists the registered strategies.
+func ScorerNames() []string {
+	names := make([]string, 0, len(scorers))
+	for name := range scorers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WeightedScorer is the original formula: a weighted sum of timeliness,
+// relevance and source weight.
+type WeightedScorer struct{}
+
+func (WeightedScorer) Score(in ScoreComponents, p ScoreParams) (ScoreComponents, Contributions) {
+	in.Timeliness = timelinessOf(in.HoursAgo, p.HalfLifeHours)
+	in.Relevance = relevanceOf(in.Hits, p.KeywordCap)
+	in.Buzz = buzzOf(in.Sources, p.BuzzCap)
+	return in, Contributions{
+		Timeliness: p.Timeliness * in.Timeliness,
+		Relevance:  p.Relevance * in.Relevance,
+		Source:     p.Source * in.SourceWeight,
+		Buzz:       p.Buzz * in.Buzz,
+	}
+}
+
+// DecayScorer multiplies the weighted relevance, source weight and buzz by
+// timeliness, so an old article sinks however relevant it is. The timeliness
+// weight is not used.
+type DecayScorer struct{}
+
+func (DecayScorer) Score(in ScoreComponents, p ScoreParams) (ScoreComponents, Contributions) {
+	in.Timeliness = timelinessOf(in.HoursAgo, p.HalfLifeHours)
+	in.Relevance = relevanceOf(in.Hits, p.KeywordCap)
+	in.Buzz = buzzOf(in.Sources, p.BuzzCap)
+	return in, Contributions{
+		Relevance: p.Relevance * in.Relevance * in.Timeliness,
+		Source:    p.Source * in.SourceWeight * in.Timeliness,
+		Buzz:      p.Buzz * in.Buzz * in.Timeliness,
+	}
+}
+
+// timelinessOf decays exponentially with age.
+func timelinessOf(hoursAgo, halfLife float64) float64 {
+	return math.Exp(-0.693 * hoursAgo / halfLife) // ln(2) ≈ 0.693
+}
+
+// relevanceOf normalises weighted keyword hits to [0, 1].
+func relevanceOf(hits float64, cap int) float64 {
+	return math.Min(hits, float64(cap)) / float64(cap)
+}
+
+// buzzOf normalises the number of sources covering a story to [0, 1]: 0 for
+// a single source, 1 once cap other sources covered it too.
+func buzzOf(sources, cap int) float64 {
+	if cap < 1 || sources <= 1 {
+		return 0
+	}
+	return float64(min(sources-1, cap)) / float64(cap)
+}
+
+// RankingOptions selects the scoring strategy and its parameters, which may be
+// overridden per category, how duplicates are detected and how the top N are
+// kept diverse.
+type RankingOptions struct {
+	Strategy   string                 `yaml:"strategy" json:"strategy"`
+	Defaults   ScoreParams            `yaml:"defaults" json:"defaults"`
+	Categories map[string]ScoreParams `yaml:"categories,omitempty" json:"categories,omitempty"`
+	Dedup      DedupOptions           `yaml:"dedup" json:"dedup"`
+	Diversity  DiversityOptions       `yaml:"diversity" json:"diversity"`
+}
+
+// DefaultRankingOptions returns the options used when the sources file has no
+// ranking section.
+func DefaultRankingOptions() RankingOptions {
+	return RankingOptions{
+		Strategy:  DefaultStrategy,
+		Defaults:  DefaultScoreParams(),
+		Dedup:     DefaultDedupOptions(),
+		Diversity: DefaultDiversityOptions(),
+	}
+}
+
+// UnmarshalYAML fills unspecified fields with their defaults; a category only
+// needs to list the parameters it changes.
+func (o *RankingOptions) UnmarshalYAML(value *yaml.Node) error {
+	var raw struct {
+		Strategy   string               `yaml:"strategy"`
+		Defaults   yaml.Node            `yaml:"defaults"`
+		Categories map[string]yaml.Node `yaml:"categories"`
+		Dedup      yaml.Node            `yaml:"dedup"`
+		Diversity  yaml.Node            `yaml:"diversity"`
+	}
+	if err := decodeStrict(value, &raw); err != nil {
+		return err
+	}
+
+	opts := DefaultRankingOptions()
+	if raw.Strategy != "" {
+		opts.Strategy = raw.Strategy
+	}
+	if !raw.Defaults.IsZero() {
+		if err := decodeStrict(&raw.Defaults, &opts.Defaults); err != nil {
+			return err
+		}
+	}
+	if !raw.Dedup.IsZero() {
+		if err := decodeStrict(&raw.Dedup, &opts.Dedup); err != nil {
+			return err
+		}
+	}
+	if !raw.Diversity.IsZero() {
+		if err := decodeStrict(&raw.Diversity, &opts.Diversity); err != nil {
+			return err
+		}
+	}
+	for name, node := range raw.Categories {
+		p := opts.Defaults
+		if err := decodeStrict(&node, &p); err != nil {
+			return fmt.Errorf("ranking.categories.%s: %w", name, err)
+		}
+		if opts.Categories == nil {
+			opts.Categories = make(map[string]ScoreParams)
+		}
+		opts.Categories[name] = p
+	}
+	*o = opts
+	return nil
+}
+
+// Params returns the parameters for category.
+func (o RankingOptions) Params(category string) ScoreParams {
+	if p, ok := o.Categories[category]; ok {
+		return p
+	}
+	return o.Defaults
+}
+
+// Scorer returns the selected strategy, falling back to the default one.
+func (o RankingOptions) Scorer() Scorer {
+	if s, ok := scorers[o.Strategy]; ok {
+		return s
+	}
+	return scorers[DefaultStrategy]
+}
+
+func (o RankingOptions) validate(categories Categories) error {
+	var errs []error
+	if _, ok := scorers[o.Strategy]; !ok {
+		errs = append(errs, fmt.Errorf("ranking.strategy: unknown strategy %q (want one of %s)",
+			o.Strategy, strings.Join(ScorerNames(), ", ")))
+	}
+	errs = append(errs, o.Defaults.validate("ranking.defaults"), o.Dedup.validate(), o.Diversity.validate())
+	for name, p := range o.Categories {
+		where := "ranking.categories." + name
+		if !categories.Has(name) {
+			errs = append(errs, fmt.Errorf("%s: unknown category", where))
+		}
+		errs = append(errs, p.validate(where))
+	}
+	return errors.Join(errs...)
+}
+
+// score fills in a's components and score with the strategy and the
+// parameters of a's category.
+func (o RankingOptions) score(a *RawArticle, in ScoreComponents) {
+	c, parts := o.Scorer().Score(in, o.Params(a.Category))
+	a.Components = c
+	a.Score = parts.Total()
+}
+
+// ParseRankingOptions decodes the ranking options recorded with a fetch run.
+// Runs made before options were recorded were scored with the defaults.
+func ParseRankingOptions(recorded string) (RankingOptions, error) {
+	if recorded == "" {
+		return DefaultRankingOptions(), nil
+	}
+	var opts RankingOptions
+	if err := json.Unmarshal([]byte(recorded), &opts); err != nil {
+		return DefaultRankingOptions(), fmt.Errorf("parse ranking options: %w", err)
+	}
+	return opts, nil
+}
+
+// RescoreCandidate recomputes a stored candidate's components and score with
+// opts and returns the parameters and contributions behind the new score.
+func RescoreCandidate(c *model.Candidate, opts RankingOptions) (ScoreParams, Contributions) {
+	p := opts.Params(c.Category)
+	in := ScoreComponents{
+		SourceWeight:  c.SourceWeight,
+		HoursAgo:      c.HoursAgo,
+		Keywords:      c.Keywords,
+		Hits:          c.Hits,
+		Sources:       c.Sources,
+		WeightDefault: c.WeightDefault,
+	}
+	out, parts := opts.Scorer().Score(in, p)
+	c.Timeliness, c.Relevance, c.Buzz = out.Timeliness, out.Relevance, out.Buzz
+	c.Score = parts.Total()
+	return p, parts
+}
+
+// RescoreCandidates re-scores a stored candidate pool with opts and sorts it
+// by the new score. Positions are renumbered within each category and as many
+// of each category get a rank as the run ranked, as if it had used opts. The
+// diversity step is not applied, since candidates do not record their feed.
+func RescoreCandidates(cands []model.Candidate, opts RankingOptions) {
+	topN := make(map[string]int)
+	for i := range cands {
+		if cands[i].Rank > 0 {
+			topN[cands[i].Category]++
+		}
+		RescoreCandidate(&cands[i], opts)
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].Score > cands[j].Score
+	})
+	positions := make(map[string]int)
+	for i := range cands {
+		positions[cands[i].Category]++
+		pos := positions[cands[i].Category]
+		cands[i].Position = pos
+		cands[i].Rank = 0
+		if pos <= topN[cands[i].Category] {
+			cands[i].Rank = pos
+		}
+	}
+}
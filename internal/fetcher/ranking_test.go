@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"top-ai-news/internal/model"
+)
+
+func TestRankingConfigUnknownFields(t *testing.T) {
+	tests := []struct{ doc, want string }{
+		{"ranking:\n  defaults:\n    half_life_hour: 2", `line 4: unknown field "half_life_hour"`},
+		{"ranking:\n  dedup: {treshold: 0.9}", `line 3: unknown field "treshold"`},
+		{"ranking:\n  diversity: {per_sorce: 1}", `unknown field "per_sorce"`},
+		{"ranking:\n  categories:\n    global: {relevence: 1}", `ranking.categories.global: line 4: unknown field "relevence"`},
+		{"ranking:\n  stratgey: weighted", `unknown field "stratgey" (want one of categories, dedup, defaults, diversity, strategy)`},
+	}
+	for _, tt := range tests {
+		wantSourcesErrors(t, "\n"+tt.doc+oneSource, tt.want)
+	}
+}
+
+func TestWeightedScorerDefaults(t *testing.T) {
+	in := ScoreComponents{HoursAgo: 6, Hits: 2, SourceWeight: 0.8, Sources: 1}
+	out, parts := WeightedScorer{}.Score(in, DefaultScoreParams())
+
+	// The formula before scorers were pluggable.
+	timeliness := math.Exp(-0.693 * 6 / 12)
+	want := 0.5*timeliness + 0.3*2/5 + 0.2*0.8
+	if math.Abs(parts.Total()-want) > 1e-9 {
+		t.Errorf("score %v, want %v", parts.Total(), want)
+	}
+	if out.Timeliness != timeliness || out.Relevance != 0.4 || out.Buzz != 0 {
+		t.Errorf("components %+v", out)
+	}
+	if math.Abs(parts.Source-0.16) > 1e-9 || parts.Buzz != 0 {
+		t.Errorf("contributions %+v", parts)
+	}
+
+	// Coverage by three other sources adds the full buzz weight.
+	in.Sources = 4
+	if _, parts := (WeightedScorer{}).Score(in, DefaultScoreParams()); math.Abs(parts.Total()-(want+0.15)) > 1e-9 {
+		t.Errorf("score with buzz %v, want %v", parts.Total(), want+0.15)
+	}
+}
+
+func TestDecayScorerHalfLife(t *testing.T) {
+	p := DefaultScoreParams()
+	p.HalfLifeHours = 6
+	in := ScoreComponents{Hits: 5, SourceWeight: 1, Sources: 2}
+	_, fresh := DecayScorer{}.Score(in, p)
+	if want := p.Relevance + p.Source + p.Buzz/3; math.Abs(fresh.Total()-want) > 1e-9 {
+		t.Fatalf("fresh score %v, want %v", fresh.Total(), want)
+	}
+	if fresh.Timeliness != 0 {
+		t.Errorf("timeliness contributes %v, want 0", fresh.Timeliness)
+	}
+	for _, halves := range []float64{1, 2, 4} {
+		in.HoursAgo = halves * p.HalfLifeHours
+		_, parts := DecayScorer{}.Score(in, p)
+		if want := fresh.Total() / math.Pow(2, halves); math.Abs(parts.Total()-want) > 1e-3*want {
+			t.Errorf("after %v hours: score %v, want %v", in.HoursAgo, parts.Total(), want)
+		}
+	}
+}
+
+func TestRankingCategoryParams(t *testing.T) {
+	set := mustParseSources(t, `
+ranking:
+  defaults: {half_life_hours: 6}
+  categories:
+    global: {relevance: 0.6}`+oneSource)
+	opts := set.Ranking
+
+	want := DefaultScoreParams()
+	want.HalfLifeHours = 6
+	if got := opts.Params("domestic"); got != want {
+		t.Errorf("domestic params %+v, want the defaults %+v", got, want)
+	}
+	want.Relevance = 0.6
+	if got := opts.Params("global"); got != want {
+		t.Errorf("global params %+v, want %+v", got, want)
+	}
+
+	in := ScoreComponents{HoursAgo: 3, Hits: 5, SourceWeight: 0.7, Sources: 1}
+	global, domestic := RawArticle{Category: "global"}, RawArticle{Category: "domestic"}
+	opts.score(&global, in)
+	opts.score(&domestic, in)
+	if diff := global.Score - domestic.Score; math.Abs(diff-0.3) > 1e-9 {
+		t.Errorf("global scores %v more than domestic, want 0.3 from its relevance weight", diff)
+	}
+}
+
+func TestRescoreCandidates(t *testing.T) {
+	cand := func(title, category string, rank int, in ScoreComponents) model.Candidate {
+		return model.Candidate{
+			News:         model.News{Title: title, Category: category, Rank: rank},
+			HoursAgo:     in.HoursAgo,
+			Hits:         in.Hits,
+			SourceWeight: in.SourceWeight,
+			Sources:      1,
+		}
+	}
+	// Stored in the order the default weighted scorer ranked them, top 2.
+	pool := []model.Candidate{
+		cand("fresh", "global", 1, ScoreComponents{HoursAgo: 0, SourceWeight: 0.5}),
+		cand("relevant", "global", 2, ScoreComponents{HoursAgo: 48, Hits: 5, SourceWeight: 1}),
+		cand("stale", "global", 0, ScoreComponents{HoursAgo: 24, SourceWeight: 0.3}),
+		cand("domestic", "domestic", 1, ScoreComponents{HoursAgo: 1, Hits: 1, SourceWeight: 0.5}),
+	}
+
+	opts := DefaultRankingOptions()
+	RescoreCandidates(pool, opts)
+	got := candidateOrder(pool)
+	if want := "domestic:1/1 fresh:1/1 relevant:2/2 stale:3/0"; got != want {
+		t.Errorf("with the stored options: %s, want %s", got, want)
+	}
+
+	p := opts.Defaults
+	p.Relevance = 1
+	opts.Categories = map[string]ScoreParams{"global": p}
+	RescoreCandidates(pool, opts)
+	if want := "relevant:1/1 domestic:1/1 fresh:2/2 stale:3/0"; candidateOrder(pool) != want {
+		t.Errorf("with relevance weighted up: %s, want %s", candidateOrder(pool), want)
+	}
+}
+
+// candidateOrder lists title:position/rank of each candidate.
+func candidateOrder(cands []model.Candidate) string {
+	var out []string
+	for _, c := range cands {
+		out = append(out, fmt.Sprintf("%s:%d/%d", c.Title, c.Position, c.Rank))
+	}
+	return strings.Join(out, " ")
+}
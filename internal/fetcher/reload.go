@@ -0,0 +1,182 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourceSet is an immutable snapshot of the active feed list.
+type SourceSet struct {
+	Path       string         `json:"path"` // empty when using the compiled-in feeds
+	LoadedAt   time.Time      `json:"loaded_at"`
+	Feeds      []FeedSource   `json:"sources"`
+	Categories Categories     `json:"categories"`
+	Fetch      FetchOptions   `json:"fetch"`
+	Ranking    RankingOptions `json:"ranking"`
+	Keywords   KeywordOptions `json:"keywords"`
+	Tags       TagRules       `json:"tags"`
+	Entities   Gazetteer      `json:"entities"`
+
+	weights map[string]float64
+	tagger  Tagger
+}
+
+func newSourceSet(path string, feeds []FeedSource, categories Categories, opts FetchOptions, ranking RankingOptions,
+	kw KeywordOptions, tags TagRules, entities Gazetteer) *SourceSet {
+	return &SourceSet{
+		Path:       path,
+		LoadedAt:   time.Now(),
+		Feeds:      feeds,
+		Categories: categories,
+		Fetch:      opts,
+		Ranking:    ranking,
+		Keywords:   kw,
+		Tags:       tags,
+		Entities:   entities,
+		weights:    BuildSourceWeights(feeds),
+		tagger:     newTagger(tags),
+	}
+}
+
+// Tagger returns the rules that classify articles of this set.
+func (s *SourceSet) Tagger() Tagger {
+	return s.tagger
+}
+
+// Sources returns the currently active source set.
+func (f *Fetcher) Sources() *SourceSet {
+	f.sourcesMu.RLock()
+	defer f.sourcesMu.RUnlock()
+	return f.active
+}
+
+// LastReloadError returns the error of the most recent failed reload, or nil
+// if the last reload succeeded.
+func (f *Fetcher) LastReloadError() error {
+	f.sourcesMu.RLock()
+	defer f.sourcesMu.RUnlock()
+	return f.reloadErr
+}
+
+// ReloadSources re-reads the sources file. An invalid file is rejected and the
+// previous set stays active. The next FetchAndStore picks up the new set.
+func (f *Fetcher) ReloadSources() error {
+	if f.sourcesPath == "" {
+		return errors.New("no sources file configured")
+	}
+	set, err := LoadSources(f.sourcesPath)
+
+	f.sourcesMu.Lock()
+	defer f.sourcesMu.Unlock()
+	f.reloadErr = err
+	if err != nil {
+		log.Printf("⚠ 重载 RSS 源配置失败，继续使用旧配置: %v", err)
+		return err
+	}
+	f.active = set
+	log.Printf("✓ 已重载 RSS 源配置 %s (%d 个源)", f.sourcesPath, len(set.Feeds))
+	return nil
+}
+
+// WatchSources polls the sources file for modifications and reloads it when
+// it changes. It does nothing when no sources file is configured.
+func (f *Fetcher) WatchSources(interval time.Duration) {
+	if f.sourcesPath == "" {
+		return
+	}
+	go func() {
+		last := fileModTime(f.sourcesPath)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				mod := fileModTime(f.sourcesPath)
+				if mod.IsZero() || mod.Equal(last) {
+					continue
+				}
+				last = mod
+				log.Printf("📝 检测到 %s 已修改，重新加载", f.sourcesPath)
+				f.ReloadSources()
+			case <-f.stopCh:
+				return
+			}
+		}
+	}()
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
+
+// ensureSourcesFile writes the compiled-in feeds to path if it does not exist.
+func ensureSourcesFile(path string) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("stat sources file: %w", err)
+	}
+
+	data, err := MarshalSources(DefaultFeeds())
+	if err != nil {
+		return err
+	}
+	header := "# RSS 源配置（由内置源自动生成）\n# 修改后自动重载，或发送 SIGHUP 立即生效\n"
+	if err := os.WriteFile(path, append([]byte(header), data...), 0o644); err != nil {
+		return fmt.Errorf("create sources file: %w", err)
+	}
+	log.Printf("✓ 已生成默认 RSS 源配置 %s", path)
+	return nil
+}
+
+// MarshalSources encodes feeds in the sources file layout.
+func MarshalSources(feeds []FeedSource) ([]byte, error) {
+	file := SourcesFile{Sources: make([]SourceEntry, 0, len(feeds))}
+	for _, fs := range feeds {
+		w := fs.Weight
+		file.Sources = append(file.Sources, SourceEntry{
+			Name:     fs.Name,
+			URL:      fs.URL,
+			Category: fs.Category,
+			AIOnly:   fs.AIOnly,
+			Weight:   &w,
+		})
+	}
+	return marshalSourcesFile(file)
+}
+
+func marshalSourcesFile(file SourcesFile) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(file); err != nil {
+		return nil, fmt.Errorf("encode sources: %w", err)
+	}
+	enc.Close()
+	return buf.Bytes(), nil
+}
+
+// writeSourcesFile replaces the sources file atomically, so the watcher never
+// sees a half-written file.
+func writeSourcesFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("write sources file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("write sources file: %w", err)
+	}
+	return nil
+}
@@ -1,21 +1,25 @@
 package fetcher
 
 import (
+	"bytes"
 	"context"
+	"io"
+	"net/http"
 	"net/url"
 	"strings"
 	"time"
+	"top-ai-news/internal/model"
 
 	"github.com/mmcdole/gofeed"
 )
 
 // FeedSource defines an RSS feed to aggregate.
 type FeedSource struct {
-	Name     string
-	URL      string
-	Category string // "domestic" or "global"
-	AIOnly   bool   // true = all items are AI-related, no filtering needed
-	Weight   float64
+	Name     string  `json:"name"`
+	URL      string  `json:"url"`
+	Category string  `json:"category"` // name of one of the configured categories
+	AIOnly   bool    `json:"ai_only"`  // true = all items are AI-related, no filtering needed
+	Weight   float64 `json:"weight"`
 }
 
 // RawArticle is an intermediate representation of a parsed RSS item.
@@ -24,9 +28,14 @@ type RawArticle struct {
 	Summary     string
 	SourceURL   string
 	SourceName  string
+	Feed        string // name of the FeedSource the article came from
 	Category    string
 	PublishDate time.Time
 	Score       float64
+	Components  ScoreComponents // the parts Score was computed from
+	Also        []RawArticle    // the same story from other sources, set by Rank
+	Tags        []string        // topic tags, set by Tagger.Classify
+	Entities    []string        // canonical names of the entities mentioned, set by Gazetteer.Extract
 }
 
 // DomesticFeeds returns pre-configured Chinese AI news sources.
@@ -48,41 +57,98 @@ func GlobalFeeds() []FeedSource {
 	}
 }
 
-// AI keywords for filtering non-pure-AI sources.
+// AI keywords for filtering non-pure-AI sources; the defaults of the filter
+// dictionaries (see KeywordTerm for the matching rules).
 var domesticKeywords = []string{
-	"ai", "人工智能", "大模型", "llm", "gpt", "deepseek", "通义", "文心",
+	"ai", "人工智能", "大模型", "llm*", "gpt*", "deepseek", "通义", "文心",
 	"机器学习", "深度学习", "神经网络", "自然语言处理", "nlp", "chatgpt",
 	"生成式", "智能体", "agent", "多模态", "diffusion", "transformer",
 	"openai", "anthropic", "claude", "gemini", "copilot", "sora",
 }
 
 var globalKeywords = []string{
-	"ai", "artificial intelligence", "llm", "openai", "anthropic", "deepmind",
-	"machine learning", "deep learning", "neural network", "gpt", "chatgpt",
-	"generative", "transformer", "diffusion", "large language model",
+	"ai", "artificial intelligence", "llm*", "openai", "anthropic", "deepmind",
+	"machine learning", "deep learning", "neural network*", "gpt*", "chatgpt",
+	"generative", "transformer*", "diffusion", "large language model*",
 	"claude", "gemini", "copilot", "midjourney", "stable diffusion",
-	"ai model", "ai agent", "foundation model", "sora", "deepseek",
+	"ai model*", "ai agent*", "foundation model*", "sora", "deepseek",
 }
 
-// FetchFeed parses a single RSS source and returns filtered articles.
-func FetchFeed(ctx context.Context, source FeedSource) ([]RawArticle, error) {
-	fp := gofeed.NewParser()
-	fp.UserAgent = "Mozilla/5.0 (compatible; TopAINews/1.0)"
+// maxFeedSize caps how much of a feed response is read into memory.
+const maxFeedSize = 10 << 20
 
-	feed, err := fp.ParseURLWithContext(source.URL, ctx)
+const userAgent = "Mozilla/5.0 (compatible; TopAINews/1.0)"
+
+var httpClient = &http.Client{}
+
+// FeedResult is the outcome of fetching a single feed.
+type FeedResult struct {
+	Articles    []RawArticle
+	Items       int // items in the feed before keyword filtering
+	StatusCode  int
+	NotModified bool             // server answered 304; Articles come from the cached body
+	Cache       *model.FeedCache // validators and body to store for the next request, nil if unchanged
+}
+
+// FetchFeed downloads a single RSS source and returns the articles that pass
+// the keyword filters of kw. When cache is non-nil its ETag/Last-Modified are sent as conditional headers;
+// a 304 response re-uses the cached body, so it yields the same items as last
+// time instead of an error.
+func FetchFeed(ctx context.Context, source FeedSource, cache *model.FeedCache, kw Keywords) (FeedResult, error) {
+	var res FeedResult
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source.URL, nil)
 	if err != nil {
-		return nil, err
+		return res, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if cache != nil && len(cache.Body) > 0 {
+		if cache.ETag != "" {
+			req.Header.Set("If-None-Match", cache.ETag)
+		}
+		if cache.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cache.LastModified)
+		}
 	}
 
-	var keywords []string
-	if !source.AIOnly {
-		if source.Category == "domestic" {
-			keywords = domesticKeywords
-		} else {
-			keywords = globalKeywords
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return res, err
+	}
+	defer resp.Body.Close()
+	res.StatusCode = resp.StatusCode
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cache != nil:
+		res.NotModified = true
+		body = cache.Body
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxFeedSize))
+		if err != nil {
+			return res, err
 		}
+		etag, lastMod := resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+		if etag != "" || lastMod != "" {
+			res.Cache = &model.FeedCache{FeedURL: source.URL, ETag: etag, LastModified: lastMod, Body: body}
+		}
+	default:
+		return res, gofeed.HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
+	feed, err := gofeed.NewParser().Parse(bytes.NewReader(body))
+	if err != nil {
+		return res, err
+	}
+	res.Items = len(feed.Items)
+	res.Articles = parseItems(feed, source, kw)
+	return res, nil
+}
+
+// parseItems converts feed items to articles, dropping items that match a
+// negative keyword and, for sources that are not AI-only, items that match no
+// AI keyword.
+func parseItems(feed *gofeed.Feed, source FeedSource, kw Keywords) []RawArticle {
 	var articles []RawArticle
 	for _, item := range feed.Items {
 		title := strings.TrimSpace(item.Title)
@@ -98,12 +164,8 @@ func FetchFeed(ctx context.Context, source FeedSource) ([]RawArticle, error) {
 			pubTime = *item.UpdatedParsed
 		}
 
-		// For non-AI-only sources, filter by keywords
-		if !source.AIOnly {
-			text := strings.ToLower(title + " " + item.Description)
-			if !matchesAnyKeyword(text, keywords) {
-				continue
-			}
+		if !kw.Admit(title+" "+item.Description, source.AIOnly) {
+			continue
 		}
 
 		// Build summary from description or content
@@ -127,22 +189,13 @@ func FetchFeed(ctx context.Context, source FeedSource) ([]RawArticle, error) {
 			Summary:     summary,
 			SourceURL:   link,
 			SourceName:  sourceName,
+			Feed:        source.Name,
 			Category:    source.Category,
 			PublishDate: pubTime,
 		})
 	}
 
-	return articles, nil
-}
-
-// matchesAnyKeyword checks if text contains any of the given keywords (case-insensitive).
-func matchesAnyKeyword(text string, keywords []string) bool {
-	for _, kw := range keywords {
-		if strings.Contains(text, kw) {
-			return true
-		}
-	}
-	return false
+	return articles
 }
 
 // stripHTML removes HTML tags and decodes common entities, returning plain text.
@@ -0,0 +1,225 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand/v2"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// ScheduleJob is what a schedule does at each of its slots.
+type ScheduleJob string
+
+const (
+	// ScheduleRefresh re-ranks the edition of the slot's date.
+	ScheduleRefresh ScheduleJob = "refresh"
+	// ScheduleFinal is the last run of the slot's edition; it locks the edition.
+	ScheduleFinal ScheduleJob = "final"
+)
+
+// DefaultSchedule refreshes every four hours on the wall clock.
+const DefaultSchedule = "refresh=0 */4 * * *"
+
+// maxCatchUpSlots bounds the slot scan when looking for a missed run.
+const maxCatchUpSlots = 10000
+
+// Schedule is a named cron expression evaluated in the edition timezone.
+type Schedule struct {
+	Name string
+	Job  ScheduleJob
+	Expr string
+	spec cron.Schedule
+}
+
+// ParseSchedule parses "[name:]job=expr", for example "refresh=0 7,12,18 * * *"
+// or "night:final=55 23 * * *". expr is a standard five-field cron expression
+// (descriptors such as @daily are accepted too). The name defaults to the job.
+func ParseSchedule(s string) (Schedule, error) {
+	key, expr, ok := strings.Cut(s, "=")
+	if !ok {
+		return Schedule{}, fmt.Errorf("schedule %q: want [name:]job=expr", s)
+	}
+	name, job, ok := strings.Cut(strings.TrimSpace(key), ":")
+	if !ok {
+		job = name
+	}
+	sc := Schedule{Name: name, Job: ScheduleJob(job), Expr: strings.TrimSpace(expr)}
+	if sc.Name == "" {
+		return Schedule{}, fmt.Errorf("schedule %q: empty name", s)
+	}
+	switch sc.Job {
+	case ScheduleRefresh, ScheduleFinal:
+	default:
+		return Schedule{}, fmt.Errorf("schedule %q: unknown job %q (want %s or %s)", s, job, ScheduleRefresh, ScheduleFinal)
+	}
+	spec, err := cron.ParseStandard(sc.Expr)
+	if err != nil {
+		return Schedule{}, fmt.Errorf("schedule %q: %w", s, err)
+	}
+	sc.spec = spec
+	return sc, nil
+}
+
+// ParseSchedules parses every spec and rejects duplicate names.
+func ParseSchedules(specs []string) ([]Schedule, error) {
+	var errs []error
+	var out []Schedule
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		sc, err := ParseSchedule(spec)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if seen[sc.Name] {
+			errs = append(errs, fmt.Errorf("schedule %q: duplicate name %q", spec, sc.Name))
+			continue
+		}
+		seen[sc.Name] = true
+		out = append(out, sc)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SchedulerOptions configures StartScheduler.
+type SchedulerOptions struct {
+	Schedules []Schedule
+	Jitter    time.Duration // random delay in [0, Jitter) added to every slot
+	CatchUp   time.Duration // on start, run the latest slot missed within this window; 0 disables
+}
+
+// StartScheduler fetches today's news on startup (if needed) and then runs
+// every schedule at its cron slots in the edition timezone. A slot is recorded
+// once its run succeeds, so a slot missed while the server was down is run on
+// the next start if it falls within opts.CatchUp.
+func (f *Fetcher) StartScheduler(opts SchedulerOptions) {
+	go func() {
+		// Initial fetch: check if today has data
+		today := f.Today()
+		has, _ := f.db.HasNewsForDate(today)
+		if !has {
+			log.Println("📡 首次启动，正在拉取今日新闻...")
+			if _, _, err := f.Enqueue(today, TriggerScheduler, false); err != nil {
+				log.Printf("首次拉取新闻失败: %v", err)
+			}
+		} else {
+			log.Println("✓ 今日新闻已存在，跳过首次拉取")
+		}
+	}()
+
+	for _, s := range opts.Schedules {
+		log.Printf("⏰ 定时任务 %s (%s): %s，下次 %s", s.Name, s.Job, s.Expr,
+			s.spec.Next(time.Now().In(f.loc)).Format("2006-01-02 15:04"))
+		go f.runSchedule(s, opts)
+	}
+}
+
+// runSchedule is the loop of a single schedule; it exits when the fetcher is stopped.
+func (f *Fetcher) runSchedule(s Schedule, opts SchedulerOptions) {
+	if slot, ok := f.missedSlot(s, time.Now().In(f.loc), opts.CatchUp); ok {
+		log.Printf("⏪ 补跑定时任务 %s 错过的时段 %s", s.Name, slot.Format("2006-01-02 15:04"))
+		if !f.runSlot(s, slot) {
+			return
+		}
+	}
+
+	for {
+		slot := s.spec.Next(time.Now().In(f.loc))
+		delay := time.Until(slot)
+		if opts.Jitter > 0 {
+			delay += time.Duration(rand.Int64N(int64(opts.Jitter)))
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+			if !f.runSlot(s, slot) {
+				return
+			}
+		case <-f.stopCh:
+			timer.Stop()
+			log.Printf("定时任务 %s 已停止", s.Name)
+			return
+		}
+	}
+}
+
+// runSlot enqueues the run for slot, waits for it and records the slot on
+// success; a successful final run locks the edition. Locked editions are left
+// alone. It returns false if the fetcher was stopped meanwhile.
+func (f *Fetcher) runSlot(s Schedule, slot time.Time) bool {
+	date := slot.In(f.loc).Format("2006-01-02")
+	if state, err := f.EditionState(date); err == nil && state == EditionLocked {
+		log.Printf("🔒 定时任务 %s: %s 已锁定，跳过", s.Name, date)
+		f.saveSlot(s, slot)
+		return true
+	}
+	if s.Job == ScheduleFinal {
+		log.Printf("📡 定时任务 %s: %s 最终抓取...", s.Name, date)
+	} else {
+		log.Printf("📡 定时任务 %s: 刷新 %s 新闻...", s.Name, date)
+	}
+
+	job, _, err := f.Enqueue(date, TriggerScheduler, false)
+	if err != nil {
+		log.Printf("定时任务 %s 拉取新闻失败: %v", s.Name, err)
+		return true
+	}
+	select {
+	case <-job.Done():
+	case <-f.stopCh:
+		return false
+	}
+	if job.Status().Status != JobSucceeded {
+		return true
+	}
+	if s.Job == ScheduleFinal {
+		if err := f.SetEditionState(date, EditionLocked); err != nil {
+			log.Printf("锁定 %s 日报失败: %v", date, err)
+			return true
+		}
+		log.Printf("🔒 %s 日报已锁定", date)
+	}
+	f.saveSlot(s, slot)
+	return true
+}
+
+func (f *Fetcher) saveSlot(s Schedule, slot time.Time) {
+	if err := f.db.SaveScheduleSlot(s.Name, slot); err != nil {
+		log.Printf("记录定时任务 %s 失败: %v", s.Name, err)
+	}
+}
+
+// missedSlot returns the latest slot of s in (last completed slot, now] that
+// is no older than window. A schedule that has never run has nothing to catch up.
+func (f *Fetcher) missedSlot(s Schedule, now time.Time, window time.Duration) (time.Time, bool) {
+	if window <= 0 {
+		return time.Time{}, false
+	}
+	last, err := f.db.GetScheduleSlot(s.Name)
+	if err != nil {
+		log.Printf("读取定时任务 %s 状态失败: %v", s.Name, err)
+		return time.Time{}, false
+	}
+	if last == nil {
+		return time.Time{}, false
+	}
+
+	from := now.Add(-window)
+	if last.After(from) {
+		from = *last
+	}
+	var missed time.Time
+	t := s.spec.Next(from.In(f.loc))
+	for i := 0; i < maxCatchUpSlots && !t.IsZero() && !t.After(now); i++ {
+		missed = t
+		t = s.spec.Next(t)
+	}
+	return missed, !missed.IsZero()
+}
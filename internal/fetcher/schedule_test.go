@@ -0,0 +1,121 @@
+package fetcher
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"top-ai-news/internal/database"
+)
+
+// newTestFetcher returns a Fetcher with the compiled-in feeds and an empty
+// database in a temporary directory.
+func newTestFetcher(t *testing.T, loc *time.Location) *Fetcher {
+	t.Helper()
+	db, err := database.New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	f, err := New(db, "", loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestParseSchedules(t *testing.T) {
+	tests := []struct {
+		specs []string
+		want  []string // name/job of each schedule, or error messages
+	}{
+		{[]string{"refresh=0 7,12,18 * * *", "night:final=55 23 * * *", "weekly:refresh=@weekly"},
+			[]string{"refresh/refresh", "night/final", "weekly/refresh"}},
+		{[]string{"refresh"}, []string{`schedule "refresh": want [name:]job=expr`}},
+		{[]string{"fetch=0 * * * *"}, []string{`schedule "fetch=0 * * * *": unknown job "fetch" (want refresh or final)`}},
+		{[]string{"night:publish=0 * * * *"}, []string{`unknown job "publish"`}},
+		{[]string{":final=0 * * * *"}, []string{`schedule ":final=0 * * * *": empty name`}},
+		{[]string{"refresh=61 * * * *"}, []string{`schedule "refresh=61 * * * *": `}},
+		{[]string{"refresh=0 * * *"}, []string{`schedule "refresh=0 * * *": `}},
+		{[]string{"refresh=0 * * * *", "refresh=30 * * * *"}, []string{`schedule "refresh=30 * * * *": duplicate name "refresh"`}},
+		{[]string{"a:refresh=0 * * * *", "a:final=55 23 * * *"}, []string{`duplicate name "a"`}},
+		// Every bad spec is reported, not just the first.
+		{[]string{"fetch=0 * * * *", "refresh=0 * * * *", "x:final=bad"}, []string{`unknown job "fetch"`, `schedule "x:final=bad"`}},
+	}
+	for _, tt := range tests {
+		scheds, err := ParseSchedules(tt.specs)
+		if err != nil {
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("%q: error %q does not mention %q", tt.specs, err, w)
+				}
+			}
+			continue
+		}
+		var got []string
+		for _, s := range scheds {
+			got = append(got, fmt.Sprintf("%s/%s", s.Name, s.Job))
+		}
+		if strings.Join(got, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("%q: schedules %q, want %q", tt.specs, got, tt.want)
+		}
+	}
+}
+
+func TestMissedSlot(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Shanghai")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f := newTestFetcher(t, loc)
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	const refresh, final = "refresh=0 */4 * * *", "final=55 23 * * *"
+	tests := []struct {
+		spec   string
+		last   string // last completed slot; "" = never ran
+		now    string
+		window time.Duration
+		want   string // "" = nothing to catch up
+	}{
+		{refresh, "", "2026-02-16 17:30", 24 * time.Hour, ""},
+		{refresh, "2026-02-16 08:00", "2026-02-16 17:30", 0, ""},
+		// Only the latest missed slot is run.
+		{refresh, "2026-02-16 08:00", "2026-02-16 17:30", 24 * time.Hour, "2026-02-16 16:00"},
+		{refresh, "2026-02-16 08:00", "2026-02-16 12:00", 24 * time.Hour, "2026-02-16 12:00"},
+		{refresh, "2026-02-16 16:00", "2026-02-16 17:30", 24 * time.Hour, ""},
+		// A slot older than the window is not caught up.
+		{refresh, "2026-02-10 08:00", "2026-02-16 17:30", 3 * time.Hour, "2026-02-16 16:00"},
+		{refresh, "2026-02-10 08:00", "2026-02-16 17:30", time.Hour, ""},
+		{final, "2026-02-15 23:55", "2026-02-17 09:00", 48 * time.Hour, "2026-02-16 23:55"},
+		{final, "2026-02-15 23:55", "2026-02-17 09:00", 6 * time.Hour, ""},
+		{final, "2026-02-16 23:55", "2026-02-17 09:00", 48 * time.Hour, ""},
+	}
+	for i, tt := range tests {
+		s, err := ParseSchedule(fmt.Sprintf("s%d:%s", i, tt.spec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.last != "" {
+			if err := f.db.SaveScheduleSlot(s.Name, at(tt.last)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		slot, ok := f.missedSlot(s, at(tt.now), tt.window)
+		switch {
+		case tt.want == "" && ok:
+			t.Errorf("%d: %s last %q, now %s, window %s: caught up %s, want nothing",
+				i, tt.spec, tt.last, tt.now, tt.window, slot.In(loc).Format("2006-01-02 15:04"))
+		case tt.want != "" && (!ok || !slot.Equal(at(tt.want))):
+			t.Errorf("%d: %s last %q, now %s, window %s: caught up %v (%v), want %s",
+				i, tt.spec, tt.last, tt.now, tt.window, slot.In(loc).Format("2006-01-02 15:04"), ok, tt.want)
+		}
+	}
+}
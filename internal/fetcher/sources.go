@@ -0,0 +1,333 @@
+package fetcher
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SourcesFile is the on-disk layout of a sources file. JSON is a subset of
+// YAML, so the same structure accepts both formats.
+type SourcesFile struct {
+	Categories Categories      `yaml:"categories,omitempty" json:"categories,omitempty"`
+	Fetch      *FetchOptions   `yaml:"fetch,omitempty" json:"fetch,omitempty"`
+	Ranking    *RankingOptions `yaml:"ranking,omitempty" json:"ranking,omitempty"`
+	Keywords   *KeywordOptions `yaml:"keywords,omitempty" json:"keywords,omitempty"`
+	Tags       TagRules        `yaml:"tags,omitempty" json:"tags,omitempty"`
+	Entities   Gazetteer       `yaml:"entities,omitempty" json:"entities,omitempty"`
+	Sources    []SourceEntry   `yaml:"sources" json:"sources"`
+}
+
+// categories returns the file's categories, or the defaults if it has none.
+func (f SourcesFile) categories() Categories {
+	if f.Categories == nil {
+		return DefaultCategories()
+	}
+	return f.Categories
+}
+
+// FetchOptions tunes retries and the per-feed circuit breaker.
+type FetchOptions struct {
+	Retries          int           `yaml:"retries" json:"retries"`                     // extra attempts after a transient failure
+	BackoffBase      time.Duration `yaml:"backoff_base" json:"backoff_base"`           // delay before the first retry, doubled each time
+	BackoffMax       time.Duration `yaml:"backoff_max" json:"backoff_max"`             // upper bound for a single delay
+	BreakerThreshold int           `yaml:"breaker_threshold" json:"breaker_threshold"` // consecutive failed runs before a feed is skipped, 0 disables
+	BreakerCooldown  time.Duration `yaml:"breaker_cooldown" json:"breaker_cooldown"`   // how long a tripped feed is skipped before a probe
+}
+
+// DefaultFetchOptions returns the options used when the sources file has no
+// fetch section.
+func DefaultFetchOptions() FetchOptions {
+	return FetchOptions{
+		Retries:          2,
+		BackoffBase:      time.Second,
+		BackoffMax:       8 * time.Second,
+		BreakerThreshold: 3,
+		BreakerCooldown:  8 * time.Hour,
+	}
+}
+
+// UnmarshalYAML fills unspecified fields with their defaults.
+func (o *FetchOptions) UnmarshalYAML(value *yaml.Node) error {
+	type plain FetchOptions
+	p := plain(DefaultFetchOptions())
+	if err := decodeStrict(value, &p); err != nil {
+		return err
+	}
+	*o = FetchOptions(p)
+	return nil
+}
+
+func (o FetchOptions) validate() error {
+	var errs []error
+	if o.Retries < 0 || o.Retries > 10 {
+		errs = append(errs, fmt.Errorf("fetch.retries %d out of range [0, 10]", o.Retries))
+	}
+	if o.BackoffBase < 0 || o.BackoffMax < 0 || o.BreakerCooldown < 0 {
+		errs = append(errs, errors.New("fetch: durations must not be negative"))
+	}
+	if o.BreakerThreshold < 0 {
+		errs = append(errs, fmt.Errorf("fetch.breaker_threshold %d must not be negative", o.BreakerThreshold))
+	}
+	return errors.Join(errs...)
+}
+
+// SourceEntry declares a single feed in a sources file.
+type SourceEntry struct {
+	Name     string   `yaml:"name" json:"name"`
+	URL      string   `yaml:"url" json:"url"`
+	Category string   `yaml:"category" json:"category"`
+	AIOnly   bool     `yaml:"ai_only" json:"ai_only"`
+	Weight   *float64 `yaml:"weight,omitempty" json:"weight,omitempty"`   // defaults to 0.7
+	Enabled  *bool    `yaml:"enabled,omitempty" json:"enabled,omitempty"` // defaults to true
+}
+
+// DefaultFeeds returns the compiled-in feed list used when no sources file is given.
+func DefaultFeeds() []FeedSource {
+	return append(DomesticFeeds(), GlobalFeeds()...)
+}
+
+// LoadSources reads and validates a sources file and returns its enabled feeds
+// with their categories, their fetch, ranking and keyword options, the tag
+// rules and the entity gazetteer. An empty
+// path falls back to DefaultFeeds.
+func LoadSources(path string) (*SourceSet, error) {
+	if path == "" {
+		return newSourceSet("", DefaultFeeds(), DefaultCategories(), DefaultFetchOptions(), DefaultRankingOptions(),
+			DefaultKeywordOptions(), DefaultTagRules(), DefaultGazetteer()), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read sources file: %w", err)
+	}
+	set, err := ParseSources(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	set.Path = path
+	return set, nil
+}
+
+// ParseSources decodes a YAML or JSON sources document, validates every entry
+// and returns the enabled feeds in file order.
+func ParseSources(data []byte) (*SourceSet, error) {
+	var file SourcesFile
+	if err := decodeSourcesFile(data, &file); err != nil {
+		return nil, err
+	}
+	categories := file.categories()
+	opts := DefaultFetchOptions()
+	if file.Fetch != nil {
+		opts = *file.Fetch
+	}
+	ranking := DefaultRankingOptions()
+	if file.Ranking != nil {
+		ranking = *file.Ranking
+	}
+	kw := KeywordOptions{}
+	if file.Keywords != nil {
+		kw = *file.Keywords
+	}
+	tags := DefaultTagRules()
+	if file.Tags != nil {
+		tags = file.Tags
+	}
+	if err := errors.Join(categories.validate(), opts.validate(), ranking.validate(categories),
+		kw.compile(categories), tags.compile(), file.Entities.compile(), ValidateSources(file.Sources, categories)); err != nil {
+		return nil, err
+	}
+
+	var feeds []FeedSource
+	for _, e := range file.Sources {
+		if e.Enabled != nil && !*e.Enabled {
+			continue
+		}
+		feeds = append(feeds, e.toFeedSource())
+	}
+	return newSourceSet("", feeds, categories, opts, ranking, kw.withDefaults(categories), tags,
+		DefaultGazetteer().merge(file.Entities)), nil
+}
+
+// decodeSourcesFile strictly decodes a sources document, rejecting unknown fields.
+func decodeSourcesFile(data []byte, file *SourcesFile) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("parse sources: %w", err)
+	}
+	return nil
+}
+
+// decodeStrict decodes node into v, rejecting unknown fields like
+// decodeSourcesFile. yaml.v3 does not pass KnownFields on to UnmarshalYAML
+// methods, so they decode their sub-nodes with this instead of Node.Decode.
+func decodeStrict(node *yaml.Node, v interface{}) error {
+	if err := knownFields(node, reflect.TypeOf(v)); err != nil {
+		return err
+	}
+	return node.Decode(v)
+}
+
+var (
+	nodeType        = reflect.TypeOf(yaml.Node{})
+	unmarshalerType = reflect.TypeOf((*yaml.Unmarshaler)(nil)).Elem()
+)
+
+// knownFields reports the mapping keys in node that t has no field for, at any
+// depth. Values decoded by an UnmarshalYAML method are left to it.
+func knownFields(node *yaml.Node, t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for node.Kind == yaml.DocumentNode && len(node.Content) == 1 {
+		node = node.Content[0]
+	}
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	child := func(n *yaml.Node, t reflect.Type) error {
+		if t == nodeType || t.Implements(unmarshalerType) || reflect.PointerTo(t).Implements(unmarshalerType) {
+			return nil
+		}
+		return knownFields(n, t)
+	}
+
+	var errs []error
+	switch {
+	case t.Kind() == reflect.Struct && node.Kind == yaml.MappingNode:
+		fields := yamlFields(t)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i]
+			ft, ok := fields[key.Value]
+			if !ok {
+				names := make([]string, 0, len(fields))
+				for name := range fields {
+					names = append(names, name)
+				}
+				slices.Sort(names)
+				errs = append(errs, fmt.Errorf("line %d: unknown field %q (want one of %s)",
+					key.Line, key.Value, strings.Join(names, ", ")))
+				continue
+			}
+			errs = append(errs, child(node.Content[i+1], ft))
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			errs = append(errs, child(item, t.Elem()))
+		}
+	case t.Kind() == reflect.Map && node.Kind == yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			errs = append(errs, child(node.Content[i], t.Elem()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// yamlFields maps the YAML keys of struct t to the types of their fields.
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		switch {
+		case name == "-":
+			continue
+		case strings.Contains(opts, "inline") && f.Type.Kind() == reflect.Struct:
+			maps.Copy(fields, yamlFields(f.Type))
+			continue
+		case name == "":
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// ValidateSources checks a list of entries against the configured categories
+// and reports every problem found, not just the first one.
+func ValidateSources(entries []SourceEntry, categories Categories) error {
+	var errs []error
+	seen := make(map[string]int)
+	enabled := 0
+
+	for i, e := range entries {
+		where := fmt.Sprintf("sources[%d]", i)
+		name := strings.TrimSpace(e.Name)
+		if name == "" {
+			errs = append(errs, fmt.Errorf("%s: name is required", where))
+		} else {
+			where = fmt.Sprintf("%s %q", where, name)
+			if j, dup := seen[name]; dup {
+				errs = append(errs, fmt.Errorf("%s: duplicate name (already defined at sources[%d])", where, j))
+			} else {
+				seen[name] = i
+			}
+		}
+
+		if err := validateFeedURL(e.URL); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", where, err))
+		}
+
+		if err := categories.unknownCategory(where, e.Category); err != nil {
+			errs = append(errs, err)
+		}
+
+		if e.Weight != nil && (*e.Weight < 0 || *e.Weight > 1) {
+			errs = append(errs, fmt.Errorf("%s: weight %.2f out of range [0, 1]", where, *e.Weight))
+		}
+
+		if e.Enabled == nil || *e.Enabled {
+			enabled++
+		}
+	}
+
+	if len(errs) == 0 && enabled == 0 {
+		errs = append(errs, errors.New("no enabled sources"))
+	}
+	return errors.Join(errs...)
+}
+
+// validateFeedURL requires an absolute http(s) URL with a host.
+func validateFeedURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return errors.New("url is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("bad url %q: %v", raw, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("bad url %q: scheme must be http or https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("bad url %q: missing host", raw)
+	}
+	return nil
+}
+
+func (e SourceEntry) toFeedSource() FeedSource {
+	w := DefaultSourceScore
+	if e.Weight != nil {
+		w = *e.Weight
+	}
+	return FeedSource{
+		Name:     strings.TrimSpace(e.Name),
+		URL:      e.URL,
+		Category: e.Category,
+		AIOnly:   e.AIOnly,
+		Weight:   w,
+	}
+}
@@ -0,0 +1,195 @@
+package fetcher
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Tag is a topic an article can be classified under.
+type Tag struct {
+	Name  string `json:"name"`  // identifier stored with articles and used by ?tag=
+	Label string `json:"label"` // display name
+}
+
+// TagRule decides whether an article is about a topic. Rules from the sources
+// file are KeywordRules; others can be added with RegisterTagRule.
+type TagRule interface {
+	Tag() Tag
+	Match(a RawArticle) bool
+}
+
+// KeywordRule tags the articles whose title or summary matches one of Terms,
+// also one of Require if it is set, and none of Exclude. The terms follow the
+// matching rules of KeywordTerm.
+type KeywordRule struct {
+	Name    string      `yaml:"name" json:"name"`
+	Label   string      `yaml:"label,omitempty" json:"label"`               // defaults to Name
+	Terms   KeywordList `yaml:"terms" json:"terms"`                         // the article must match one
+	Require KeywordList `yaml:"require,omitempty" json:"require,omitempty"` // and, if set, one of these
+	Exclude KeywordList `yaml:"exclude,omitempty" json:"exclude,omitempty"` // and none of these
+}
+
+// Tag returns the tag the rule assigns.
+func (r *KeywordRule) Tag() Tag {
+	label := r.Label
+	if label == "" {
+		label = r.Name
+	}
+	return Tag{Name: r.Name, Label: label}
+}
+
+// Match reports whether a is about the rule's topic.
+func (r *KeywordRule) Match(a RawArticle) bool {
+	text := a.Title + " " + a.Summary
+	return r.Terms.Any(text) &&
+		(len(r.Require) == 0 || r.Require.Any(text)) &&
+		!r.Exclude.Any(text)
+}
+
+func (r *KeywordRule) compile(where string) error {
+	errs := []error{
+		r.Terms.compile(where + ".terms"),
+		r.Require.compile(where + ".require"),
+		r.Exclude.compile(where + ".exclude"),
+	}
+	if len(r.Terms) == 0 {
+		errs = append(errs, fmt.Errorf("%s: terms is required", where))
+	}
+	return errors.Join(errs...)
+}
+
+// TagRules are the tag rules of a sources file, in display order.
+type TagRules []KeywordRule
+
+// DefaultTagRules returns the rules used when the sources file has no tags
+// section. It panics if a compiled-in rule does not compile.
+func DefaultTagRules() TagRules {
+	rules := TagRules{
+		{
+			Name: "llm-release", Label: "模型发布",
+			Terms: terms([]string{"发布", "推出", "上线", "亮相", "开放", "launch*", "release*", "unveil*",
+				"introduc*", "debut*", "rolls out", "roll out", "announc*", "now available"}),
+			Require: terms([]string{"大模型", "模型", "llm*", "gpt*", "claude", "gemini", "llama", "qwen", "通义",
+				"文心", "deepseek", "mistral", "grok", "model", "models"}),
+		},
+		{
+			Name: "funding", Label: "融资",
+			Terms: append(terms([]string{"融资", "估值", "领投", "跟投", "天使轮", "收购", "funding", "valuation",
+				"series a", "series b", "series c", "series d", "seed round", "investors", "acquisition*", "acquire*"}),
+				KeywordTerm{Term: `\brais(es|ed|ing)\s+(\$|€|£|¥)?\d`, Weight: 1, Regex: true}),
+		},
+		{
+			Name: "chips", Label: "芯片",
+			Terms: terms([]string{"芯片", "算力", "半导体", "英伟达", "台积电", "昇腾", "光刻", "gpu*", "tpu*",
+				"chip", "chips", "chipmaker*", "semiconductor*", "nvidia", "tsmc", "h100", "h200", "b200", "blackwell"}),
+		},
+		{
+			Name: "policy", Label: "政策监管",
+			Terms: terms([]string{"监管", "政策", "法规", "立法", "网信办", "管理办法", "备案", "禁令", "合规",
+				"regulat*", "legislation", "lawmaker*", "ai act", "policy", "policies", "executive order",
+				"senate", "congress", "antitrust", "ftc", "compliance"}),
+		},
+		{
+			Name: "research", Label: "研究论文",
+			Terms: terms([]string{"论文", "研究团队", "研究人员", "研究者", "顶会", "arxiv", "paper", "papers",
+				"study", "researchers", "neurips", "icml", "iclr", "cvpr", "peer-reviewed"}),
+		},
+		{
+			Name: "open-source", Label: "开源",
+			Terms: terms([]string{"开源", "open source", "open-source*", "open weight*", "open-weight*",
+				"github", "hugging face", "huggingface", "apache 2.0"}),
+		},
+	}
+	if err := rules.compile(); err != nil {
+		panic("default tag rules: " + err.Error())
+	}
+	return rules
+}
+
+// compile prepares every rule and reports the invalid ones.
+func (t TagRules) compile() error {
+	var errs []error
+	seen := make(map[string]int)
+	for i := range t {
+		where := fmt.Sprintf("tags[%d]", i)
+		if !identifier.MatchString(t[i].Name) {
+			errs = append(errs, fmt.Errorf("%s: name %q must be lower-case letters, digits, '-' or '_'", where, t[i].Name))
+			continue
+		}
+		where = fmt.Sprintf("%s %q", where, t[i].Name)
+		if j, dup := seen[t[i].Name]; dup {
+			errs = append(errs, fmt.Errorf("%s: duplicate name (already defined at tags[%d])", where, j))
+		} else {
+			seen[t[i].Name] = i
+		}
+		errs = append(errs, t[i].compile(where))
+	}
+	return errors.Join(errs...)
+}
+
+var (
+	tagRulesMu sync.RWMutex
+	tagRules   []TagRule
+)
+
+// RegisterTagRule adds a rule that is applied after the rules of the sources
+// file. A rule for a tag the sources file already defines adds to it. Rules
+// take effect when the sources are next loaded, so register them from init.
+func RegisterTagRule(r TagRule) {
+	tagRulesMu.Lock()
+	defer tagRulesMu.Unlock()
+	tagRules = append(tagRules, r)
+}
+
+// Tagger classifies articles with an ordered list of rules.
+type Tagger []TagRule
+
+// newTagger combines the configured rules with the registered ones.
+func newTagger(rules TagRules) Tagger {
+	tagRulesMu.RLock()
+	defer tagRulesMu.RUnlock()
+	t := make(Tagger, 0, len(rules)+len(tagRules))
+	for i := range rules {
+		t = append(t, &rules[i])
+	}
+	return append(t, tagRules...)
+}
+
+// Classify returns the names of the tags a matches, in rule order.
+func (t Tagger) Classify(a RawArticle) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, r := range t {
+		name := r.Tag().Name
+		if !seen[name] && r.Match(a) {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	}
+	return tags
+}
+
+// Tags lists the tags the rules can assign, in rule order.
+func (t Tagger) Tags() []Tag {
+	var tags []Tag
+	seen := make(map[string]bool)
+	for _, r := range t {
+		if tag := r.Tag(); !seen[tag.Name] {
+			seen[tag.Name] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// tag classifies ranked articles and the other sources' articles of their
+// stories.
+func (t Tagger) tag(ranked []RawArticle) {
+	for i := range ranked {
+		ranked[i].Tags = t.Classify(ranked[i])
+		for j := range ranked[i].Also {
+			ranked[i].Also[j].Tags = t.Classify(ranked[i].Also[j])
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package fetcher
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDefaultTagRules(t *testing.T) {
+	tagger := newTagger(DefaultTagRules())
+	tests := []struct {
+		title string
+		want  []string
+	}{
+		{"OpenAI launches GPT-5 with a 1M token context window", []string{"llm-release"}},
+		{"阿里发布通义千问Qwen3大模型并宣布开源", []string{"llm-release", "open-source"}},
+		{"Mistral raises $640 million at a $6 billion valuation", []string{"funding"}},
+		{"月之暗面完成10亿美元融资，阿里领投", []string{"funding"}},
+		{"Nvidia unveils Blackwell B200 GPU", []string{"chips"}},
+		{"EU lawmakers approve the AI Act", []string{"policy"}},
+		{"网信办发布生成式人工智能服务管理办法", []string{"policy"}},
+		{"Google researchers publish a paper on long-context attention", []string{"research"}},
+		{"Meta releases Llama 3 as an open-weight model on Hugging Face", []string{"llm-release", "open-source"}},
+		// A release that is not a model, and a raise that is not funding.
+		{"Apple releases iOS 18 with new AI features", nil},
+		{"Critics raise concerns over AI hiring tools", nil},
+	}
+	for _, tt := range tests {
+		if got := tagger.Classify(RawArticle{Title: tt.title}); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: tags %v, want %v", tt.title, got, tt.want)
+		}
+	}
+}
+
+type sourceRule struct{ source string }
+
+func (r sourceRule) Tag() Tag                { return Tag{Name: "research", Label: "研究论文"} }
+func (r sourceRule) Match(a RawArticle) bool { return a.SourceName == r.source }
+
+func TestTagRulesConfig(t *testing.T) {
+	set := mustParseSources(t, `
+tags:
+  - name: agents
+    label: 智能体
+    terms: [agent*, 智能体]
+    exclude: [travel agent*]
+  - name: research
+    terms: [paper]`+oneSource)
+	tagger := set.Tagger()
+	if got := tagger.Classify(RawArticle{Title: "Travel agents embrace AI agents"}); got != nil {
+		t.Errorf("excluded article tagged %v", got)
+	}
+	if got := tagger.Classify(RawArticle{Title: "OpenAI ships an agent for research"}); !reflect.DeepEqual(got, []string{"agents"}) {
+		t.Errorf("tags %v, want [agents]; configured rules replace the defaults", got)
+	}
+
+	// A registered rule adds to a configured tag.
+	tagRulesMu.Lock()
+	saved := tagRules
+	tagRulesMu.Unlock()
+	defer func() {
+		tagRulesMu.Lock()
+		tagRules = saved
+		tagRulesMu.Unlock()
+	}()
+	RegisterTagRule(sourceRule{source: "arxiv.org"})
+	tagger = newTagger(set.Tags)
+	if got := tagger.Classify(RawArticle{Title: "Scaling laws revisited", SourceName: "arxiv.org"}); !reflect.DeepEqual(got, []string{"research"}) {
+		t.Errorf("tags %v, want [research] from the registered rule", got)
+	}
+	if got := tagger.Tags(); !reflect.DeepEqual(got, []Tag{{"agents", "智能体"}, {"research", "research"}}) {
+		t.Errorf("catalog %v", got)
+	}
+}
+
+func TestTagRulesConfigErrors(t *testing.T) {
+	wantSourcesErrors(t, `
+tags:
+  - {name: Funding, terms: [raise]}
+  - {name: chips}
+  - {name: chips, terms: ['(gpu', {term: x, regex: true}]}`+oneSource,
+		`name "Funding" must be`, `tags[1] "chips": terms is required`,
+		`tags[2] "chips": duplicate name`)
+}
@@ -0,0 +1,292 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"top-ai-news/internal/database"
+	"top-ai-news/internal/fetcher"
+	"top-ai-news/internal/model"
+)
+
+type AdminHandler struct {
+	db      *database.DB
+	fetcher *fetcher.Fetcher
+}
+
+func NewAdminHandler(db *database.DB, f *fetcher.Fetcher) *AdminHandler {
+	return &AdminHandler{db: db, fetcher: f}
+}
+
+// GetSources reports the active feed list and when it was loaded.
+func (h *AdminHandler) GetSources(w http.ResponseWriter, r *http.Request) {
+	set := h.fetcher.Sources()
+
+	resp := struct {
+		*fetcher.SourceSet
+		Count       int    `json:"count"`
+		ReloadError string `json:"reload_error,omitempty"`
+	}{
+		SourceSet: set,
+		Count:     len(set.Feeds),
+	}
+	if err := h.fetcher.LastReloadError(); err != nil {
+		resp.ReloadError = err.Error()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// ExportOPML returns the active sources as an OPML 2.0 document.
+func (h *AdminHandler) ExportOPML(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/x-opml; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="sources.opml"`)
+	if err := fetcher.ExportOPML(w, h.fetcher.Sources().Feeds); err != nil {
+		log.Printf("导出 OPML 失败: %v", err)
+	}
+}
+
+// ImportOPML merges an uploaded OPML document into the sources file.
+// ?category= sets the category for feeds outside a category folder.
+func (h *AdminHandler) ImportOPML(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, 1<<20)
+	added, err := h.fetcher.ImportOPML(r.Body, r.URL.Query().Get("category"))
+	if err != nil {
+		http.Error(w, "导入 OPML 失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status": "ok",
+		"added":  added,
+	})
+}
+
+// GetBreakers lists the circuit-breaker state of every feed that has failed.
+func (h *AdminHandler) GetBreakers(w http.ResponseWriter, r *http.Request) {
+	breakers, err := h.db.ListFeedBreakers()
+	if err != nil {
+		http.Error(w, "获取熔断状态失败", http.StatusInternalServerError)
+		return
+	}
+
+	type breakerStatus struct {
+		model.FeedBreaker
+		State   string     `json:"state"`
+		RetryAt *time.Time `json:"retry_at,omitempty"`
+	}
+
+	opts := h.fetcher.Sources().Fetch
+	now := time.Now()
+	list := make([]breakerStatus, 0, len(breakers))
+	for i := range breakers {
+		b := breakerStatus{FeedBreaker: breakers[i], State: fetcher.BreakerStatus(&breakers[i], opts, now)}
+		if b.OpenedAt != nil {
+			retry := b.OpenedAt.Add(opts.BreakerCooldown)
+			b.RetryAt = &retry
+		}
+		list = append(list, b)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// GetRuns lists recent fetch runs with per-source results. ?limit= defaults to 20.
+func (h *AdminHandler) GetRuns(w http.ResponseWriter, r *http.Request) {
+	limit := 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 200 {
+			http.Error(w, "limit 参数无效 (1-200)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	runs, err := h.db.ListFetchRuns(limit)
+	if err != nil {
+		http.Error(w, "获取抓取记录失败", http.StatusInternalServerError)
+		return
+	}
+	if runs == nil {
+		runs = []model.FetchRun{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(runs)
+}
+
+// GetSourceHealth aggregates per-source fetch results. ?days= sets the window,
+// 7 by default.
+func (h *AdminHandler) GetSourceHealth(w http.ResponseWriter, r *http.Request) {
+	days := 7
+	if v := r.URL.Query().Get("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 365 {
+			http.Error(w, "days 参数无效 (1-365)", http.StatusBadRequest)
+			return
+		}
+		days = n
+	}
+
+	health, err := h.db.GetSourceHealth(time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		http.Error(w, "获取来源健康状况失败", http.StatusInternalServerError)
+		return
+	}
+	if health == nil {
+		health = []model.SourceHealth{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"days":    days,
+		"sources": health,
+	})
+}
+
+// Backfill queues a rebuild of the rankings for ?from=&to= (inclusive, yyyy-MM-dd).
+// Locked days are skipped unless ?force=1 is given.
+func (h *AdminHandler) Backfill(w http.ResponseWriter, r *http.Request) {
+	from, to := r.URL.Query().Get("from"), r.URL.Query().Get("to")
+	if to == "" {
+		to = from
+	}
+
+	job, coalesced, err := h.fetcher.EnqueueBackfill(from, to, fetcher.TriggerAPI, forceParam(r))
+	if err == fetcher.ErrQueueFull {
+		http.Error(w, "抓取任务队列已满，请稍后再试", http.StatusServiceUnavailable)
+		return
+	}
+	if err != nil {
+		http.Error(w, "参数无效: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	st := job.Status()
+	log.Printf("已创建回填任务 %s (%s)", st.ID, st.Date)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/news/fetch/"+st.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":    st.ID,
+		"status":    st.Status,
+		"date":      st.Date,
+		"coalesced": coalesced,
+	})
+}
+
+// GetEditions lists the most recent editions and their states. ?limit= defaults to 30.
+func (h *AdminHandler) GetEditions(w http.ResponseWriter, r *http.Request) {
+	limit := 30
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 365 {
+			http.Error(w, "limit 参数无效 (1-365)", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	editions, err := h.db.ListEditions(limit)
+	if err != nil {
+		http.Error(w, "获取日报状态失败", http.StatusInternalServerError)
+		return
+	}
+	if editions == nil {
+		editions = []model.Edition{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(editions)
+}
+
+// SetEditionState changes the state of an edition to ?state=draft|published|locked.
+// Route: /api/admin/editions/{date}
+func (h *AdminHandler) SetEditionState(w http.ResponseWriter, r *http.Request) {
+	date := strings.TrimPrefix(r.URL.Path, "/api/admin/editions/")
+	state := r.URL.Query().Get("state")
+	if !fetcher.ValidEditionState(state) {
+		http.Error(w, "state 参数无效 (draft/published/locked)", http.StatusBadRequest)
+		return
+	}
+	if err := h.fetcher.SetEditionState(date, state); err != nil {
+		http.Error(w, "设置日报状态失败: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Printf("✓ %s 日报状态已设为 %s", date, state)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"date":  date,
+		"state": state,
+	})
+}
+
+// GetCandidates lists every candidate of a day's latest fetch run, highest
+// score first, with the components of each score. ?date= is required;
+// ?run_id= selects an earlier run and ?category= filters the pool; ?strategy=
+// re-scores and re-ranks the pool with another strategy for comparison.
+func (h *AdminHandler) GetCandidates(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	date := q.Get("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "日期格式无效，请使用 yyyy-MM-dd", http.StatusBadRequest)
+		return
+	}
+
+	var runID int64
+	if v := q.Get("run_id"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n <= 0 {
+			http.Error(w, "run_id 参数无效", http.StatusBadRequest)
+			return
+		}
+		runID = n
+	} else {
+		latest, err := h.db.LatestCandidateRun(date)
+		if err != nil {
+			http.Error(w, "获取候选新闻失败", http.StatusInternalServerError)
+			return
+		}
+		runID = latest
+	}
+
+	cands, err := h.db.ListCandidates(runID, date, q.Get("category"))
+	if err != nil {
+		http.Error(w, "获取候选新闻失败", http.StatusInternalServerError)
+		return
+	}
+	if cands == nil {
+		cands = []model.Candidate{}
+	}
+
+	opts, err := rankingOptions(h.db, runID, r)
+	if errors.Is(err, errUnknownStrategy) {
+		http.Error(w, "未知的排序策略", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "获取候选新闻失败", http.StatusInternalServerError)
+		return
+	}
+	if q.Get("strategy") != "" {
+		fetcher.RescoreCandidates(cands, opts)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"date":       date,
+		"run_id":     runID,
+		"ranking":    opts,
+		"count":      len(cands),
+		"candidates": cands,
+	})
+}
@@ -80,6 +80,31 @@ func (h *CommentHandler) PostComment(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// DeleteComment removes a comment. Route: /api/comments/{id}
+func (h *CommentHandler) DeleteComment(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/api/comments/"), 10, 64)
+	if err != nil {
+		http.Error(w, "无效的评论ID", http.StatusBadRequest)
+		return
+	}
+
+	found, err := h.db.DeleteComment(id)
+	if err != nil {
+		http.Error(w, "删除评论失败", http.StatusInternalServerError)
+		return
+	}
+	if !found {
+		http.Error(w, "评论不存在", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":      id,
+		"message": "评论已删除",
+	})
+}
+
 func parseNewsID(path, prefix, suffix string) (int64, error) {
 	path = strings.TrimPrefix(path, prefix)
 	path = strings.TrimSuffix(path, suffix)
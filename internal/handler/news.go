@@ -2,11 +2,16 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 	"top-ai-news/internal/database"
 	"top-ai-news/internal/fetcher"
+	"top-ai-news/internal/model"
 )
 
 type NewsHandler struct {
@@ -18,8 +23,59 @@ func NewNewsHandler(db *database.DB, f *fetcher.Fetcher) *NewsHandler {
 	return &NewsHandler{db: db, fetcher: f}
 }
 
+// newsItem is a news card as the public API returns it.
+type newsItem struct {
+	ID           int64  `json:"id"`
+	Title        string `json:"title"`
+	Summary      string `json:"summary"`
+	SourceURL    string `json:"source_url"`
+	SourceName   string `json:"source_name"`
+	Category     string `json:"category"`
+	PublishDate  string `json:"publish_date"`
+	Rank         int    `json:"rank"`
+	CommentCount int    `json:"comment_count"`
+
+	AlsoCoveredBy []model.Coverage `json:"also_covered_by"`
+	Tags          []fetcher.Tag    `json:"tags"`
+	Entities      []entityRef      `json:"entities"`
+}
+
+// entityRef names an entity an article mentions.
+type entityRef struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// newsItem turns n into a card, labelling its tags from catalog and its
+// entities from gazetteer.
+func (h *NewsHandler) newsItem(n model.News, catalog []fetcher.Tag, gazetteer fetcher.Gazetteer) newsItem {
+	count, _ := h.db.GetCommentCount(n.ID)
+	item := newsItem{
+		ID:           n.ID,
+		Title:        n.Title,
+		Summary:      n.Summary,
+		SourceURL:    n.SourceURL,
+		SourceName:   n.SourceName,
+		Category:     n.Category,
+		PublishDate:  n.PublishDate,
+		Rank:         n.Rank,
+		CommentCount: count,
+
+		AlsoCoveredBy: n.AlsoCoveredBy,
+		Tags:          tagsOf(n.Tags, catalog),
+		Entities:      entitiesOf(n.Entities, gazetteer),
+	}
+	if item.AlsoCoveredBy == nil {
+		item.AlsoCoveredBy = []model.Coverage{}
+	}
+	return item
+}
+
+// GetNews returns the ranking of ?date= (the latest by default) in one section
+// per category; ?tag= keeps only the news with that topic tag.
 func (h *NewsHandler) GetNews(w http.ResponseWriter, r *http.Request) {
 	date := r.URL.Query().Get("date")
+	tag := r.URL.Query().Get("tag")
 	if date == "" {
 		latest, err := h.db.GetLatestDate()
 		if err != nil {
@@ -27,6 +83,9 @@ func (h *NewsHandler) GetNews(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		date = latest
+		if date == "" {
+			date = h.fetcher.Today()
+		}
 	}
 	if _, err := time.Parse("2006-01-02", date); err != nil {
 		http.Error(w, "日期格式无效，请使用 yyyy-MM-dd", http.StatusBadRequest)
@@ -39,58 +98,65 @@ func (h *NewsHandler) GetNews(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	type section struct {
+		Category string     `json:"category"`
+		Label    string     `json:"label"`
+		Icon     string     `json:"icon,omitempty"`
+		News     []newsItem `json:"news"`
+	}
+
 	resp := struct {
-		Date     string      `json:"date"`
-		Domestic interface{} `json:"domestic"`
-		Global   interface{} `json:"global"`
-		HasPrev  bool        `json:"has_prev"`
-		HasNext  bool        `json:"has_next"`
+		Date     string        `json:"date"`
+		Tag      string        `json:"tag,omitempty"` // the ?tag= filter
+		Tags     []fetcher.Tag `json:"tags"`          // every tag the rules can assign, for filtering
+		Sections []*section    `json:"sections"`      // configured categories first, in order
+		HasPrev  bool          `json:"has_prev"`
+		HasNext  bool          `json:"has_next"`
+		State    string        `json:"state"`    // edition state: draft, published or locked
+		Today    string        `json:"today"`    // current edition date in the edition timezone
+		Timezone string        `json:"timezone"` // IANA name of the edition timezone
 	}{
 		Date:     date,
-		Domestic: []interface{}{},
-		Global:   []interface{}{},
+		Tag:      tag,
+		Sections: []*section{},
+		Today:    h.fetcher.Today(),
+		Timezone: h.fetcher.Location().String(),
 	}
 
-	type newsWithComments struct {
-		ID           int64  `json:"id"`
-		Title        string `json:"title"`
-		Summary      string `json:"summary"`
-		SourceURL    string `json:"source_url"`
-		SourceName   string `json:"source_name"`
-		Category     string `json:"category"`
-		PublishDate  string `json:"publish_date"`
-		Rank         int    `json:"rank"`
-		CommentCount int    `json:"comment_count"`
+	// Every configured category gets a section, even an empty one; categories
+	// that were removed from the configuration keep theirs on earlier dates.
+	sections := make(map[string]*section)
+	addSection := func(c fetcher.Category) *section {
+		s := &section{Category: c.Name, Label: c.Label, Icon: c.Icon, News: []newsItem{}}
+		sections[c.Name] = s
+		resp.Sections = append(resp.Sections, s)
+		return s
+	}
+	set := h.fetcher.Sources()
+	for _, c := range set.Categories {
+		addSection(c)
+	}
+	resp.Tags = set.Tagger().Tags()
+	if resp.Tags == nil {
+		resp.Tags = []fetcher.Tag{}
 	}
 
-	var domestic, global []newsWithComments
 	for _, n := range newsList {
-		count, _ := h.db.GetCommentCount(n.ID)
-		item := newsWithComments{
-			ID:           n.ID,
-			Title:        n.Title,
-			Summary:      n.Summary,
-			SourceURL:    n.SourceURL,
-			SourceName:   n.SourceName,
-			Category:     n.Category,
-			PublishDate:  n.PublishDate,
-			Rank:         n.Rank,
-			CommentCount: count,
+		if tag != "" && !slices.Contains(n.Tags, tag) {
+			continue
 		}
-		if n.Category == "domestic" {
-			domestic = append(domestic, item)
-		} else {
-			global = append(global, item)
+		item := h.newsItem(n, resp.Tags, set.Entities)
+		s, ok := sections[n.Category]
+		if !ok {
+			s = addSection(fetcher.Category{Name: n.Category, Label: n.Category})
 		}
+		s.News = append(s.News, item)
 	}
 
-	if domestic != nil {
-		resp.Domestic = domestic
-	}
-	if global != nil {
-		resp.Global = global
+	if resp.State, err = h.fetcher.EditionState(date); err != nil {
+		http.Error(w, "获取日报状态失败", http.StatusInternalServerError)
+		return
 	}
-
 	_, resp.HasPrev = h.db.GetPrevDate(date)
 	_, resp.HasNext = h.db.GetNextDate(date)
 
@@ -98,6 +164,60 @@ func (h *NewsHandler) GetNews(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(resp)
 }
 
+// EntityNews lists the ranked news that mention an entity, newest first.
+// {name} is the canonical name or an alias; ?limit= defaults to 20 and
+// ?offset= to 0.
+// Route: /api/entities/{name}/news
+func (h *NewsHandler) EntityNews(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/api/entities/"), "/news")
+	if name == "" || strings.Contains(name, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	limit, offset, msg := pageParams(r)
+	if msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	set := h.fetcher.Sources()
+	// Entities removed from the gazetteer keep the news they were stored with.
+	entity, known := set.Entities.Lookup(name)
+	if !known {
+		entity = fetcher.Entity{Name: name}
+	}
+	newsList, total, err := h.db.GetNewsByEntity(entity.Name, limit, offset)
+	if err != nil {
+		http.Error(w, "获取新闻失败", http.StatusInternalServerError)
+		return
+	}
+	if !known && total == 0 {
+		http.Error(w, "未知的实体", http.StatusNotFound)
+		return
+	}
+
+	resp := struct {
+		Entity entityRef  `json:"entity"`
+		Total  int        `json:"total"`
+		Limit  int        `json:"limit"`
+		Offset int        `json:"offset"`
+		News   []newsItem `json:"news"`
+	}{
+		Entity: entityRef{Name: entity.Name, Type: entity.Type},
+		Total:  total,
+		Limit:  limit,
+		Offset: offset,
+		News:   []newsItem{},
+	}
+	catalog := set.Tagger().Tags()
+	for _, n := range newsList {
+		resp.News = append(resp.News, h.newsItem(n, catalog, set.Entities))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
 func (h *NewsHandler) GetDates(w http.ResponseWriter, r *http.Request) {
 	dates, err := h.db.GetAllDates()
 	if err != nil {
@@ -108,31 +228,288 @@ func (h *NewsHandler) GetDates(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(dates)
 }
 
+// FetchNews queues a fetch run and returns 202 with the job. A request for a
+// date that is already being fetched returns the existing job. A locked date is
+// refused with 409 unless ?force=1 is given.
 func (h *NewsHandler) FetchNews(w http.ResponseWriter, r *http.Request) {
 	date := r.URL.Query().Get("date")
 	if date == "" {
-		date = time.Now().Format("2006-01-02")
+		date = h.fetcher.Today()
 	}
 	if _, err := time.Parse("2006-01-02", date); err != nil {
 		http.Error(w, "日期格式无效", http.StatusBadRequest)
 		return
 	}
+	force := forceParam(r)
+	if !force {
+		state, err := h.fetcher.EditionState(date)
+		if err != nil {
+			http.Error(w, "获取日报状态失败", http.StatusInternalServerError)
+			return
+		}
+		if state == fetcher.EditionLocked {
+			http.Error(w, "该日期已锁定，如需重写请加 force=1", http.StatusConflict)
+			return
+		}
+	}
 
-	log.Printf("开始抓取 %s 的 AI 新闻...", date)
-	if err := h.fetcher.FetchAndStore(date); err != nil {
-		log.Printf("抓取新闻失败: %v", err)
-		http.Error(w, "抓取新闻失败: "+err.Error(), http.StatusInternalServerError)
+	job, coalesced, err := h.fetcher.Enqueue(date, fetcher.TriggerAPI, force)
+	if err != nil {
+		log.Printf("创建抓取任务失败: %v", err)
+		http.Error(w, "抓取任务队列已满，请稍后再试", http.StatusServiceUnavailable)
 		return
 	}
+	st := job.Status()
+	if coalesced {
+		log.Printf("%s 的抓取任务 %s 已在进行中，合并请求", date, st.ID)
+	} else if force {
+		log.Printf("已创建 %s 的强制抓取任务 %s", date, st.ID)
+	} else {
+		log.Printf("已创建 %s 的抓取任务 %s", date, st.ID)
+	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status":  "ok",
-		"message": "新闻抓取完成",
-		"date":    date,
+	w.Header().Set("Location", "/api/news/fetch/"+st.ID)
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"job_id":    st.ID,
+		"status":    st.Status,
+		"date":      date,
+		"coalesced": coalesced,
 	})
 }
 
+// GetFetchJob reports the status and per-source progress of a fetch job.
+// Route: /api/news/fetch/{jobID}
+func (h *NewsHandler) GetFetchJob(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(r.URL.Path, "/api/news/fetch/")
+	job, ok := h.fetcher.Job(id)
+	if !ok {
+		http.Error(w, "抓取任务不存在", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job.Status())
+}
+
+// scorePart is one weighted component of a score explanation.
+type scorePart struct {
+	Value        float64 `json:"value"`
+	Weight       float64 `json:"weight"`
+	Contribution float64 `json:"contribution"` // what the component added to the score
+}
+
+// rankingOptions returns the ranking options runID scored with, switched to
+// the strategy named by ?strategy= if given.
+func rankingOptions(db *database.DB, runID int64, r *http.Request) (fetcher.RankingOptions, error) {
+	recorded, err := db.GetFetchRunRanking(runID)
+	if err != nil {
+		return fetcher.RankingOptions{}, err
+	}
+	opts, err := fetcher.ParseRankingOptions(recorded)
+	if err != nil {
+		return opts, err
+	}
+	if name := r.URL.Query().Get("strategy"); name != "" {
+		if _, ok := fetcher.ScorerByName(name); !ok {
+			return opts, errUnknownStrategy
+		}
+		opts.Strategy = name
+	}
+	return opts, nil
+}
+
+var errUnknownStrategy = errors.New("unknown ranking strategy")
+
+// Explain breaks down an article's score from the latest run it was a
+// candidate in; ?date= restricts it to that day's ranking and ?strategy=
+// re-scores it, and its run's pool for its position and rank, with another
+// strategy.
+// Route: /api/news/{id}/explain
+func (h *NewsHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	id, err := parseNewsID(r.URL.Path, "/api/news/", "/explain")
+	if err != nil {
+		http.Error(w, "无效的新闻ID", http.StatusBadRequest)
+		return
+	}
+	date := r.URL.Query().Get("date")
+	if date != "" {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			http.Error(w, "日期格式无效，请使用 yyyy-MM-dd", http.StatusBadRequest)
+			return
+		}
+	}
+
+	c, total, err := h.db.GetCandidate(id, date)
+	if err != nil {
+		http.Error(w, "获取评分明细失败", http.StatusInternalServerError)
+		return
+	}
+	if c == nil {
+		http.Error(w, "没有该新闻的评分记录", http.StatusNotFound)
+		return
+	}
+	opts, err := rankingOptions(h.db, c.RunID, r)
+	if errors.Is(err, errUnknownStrategy) {
+		http.Error(w, "未知的排序策略", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, "获取评分明细失败", http.StatusInternalServerError)
+		return
+	}
+	if r.URL.Query().Get("strategy") != "" {
+		// The place in the pool changes with the strategy too.
+		pool, err := h.db.ListCandidates(c.RunID, c.PublishDate, c.Category)
+		if err != nil {
+			http.Error(w, "获取评分明细失败", http.StatusInternalServerError)
+			return
+		}
+		fetcher.RescoreCandidates(pool, opts)
+		for _, p := range pool {
+			if p.ID == c.ID {
+				c.Position, c.Rank = p.Position, p.Rank
+			}
+		}
+	}
+	params, parts := fetcher.RescoreCandidate(c, opts)
+
+	type timeliness struct {
+		HoursAgo      float64 `json:"hours_ago"`
+		HalfLifeHours float64 `json:"half_life_hours"`
+		scorePart
+	}
+	type relevance struct {
+		Keywords []string `json:"keywords"`
+		Hits     float64  `json:"hits"` // sum of the keyword weights
+		MaxHits  int      `json:"max_hits"`
+		scorePart
+	}
+	type source struct {
+		Name    string `json:"name"`
+		Default bool   `json:"default"` // no configured weight, the default was used
+		scorePart
+	}
+	type buzz struct {
+		Sources int `json:"sources"` // sources that covered the story
+		Cap     int `json:"cap"`     // other sources that give full buzz
+		scorePart
+	}
+	resp := struct {
+		ID         int64      `json:"id"`
+		Title      string     `json:"title"`
+		Date       string     `json:"date"`
+		RunID      int64      `json:"run_id"`
+		Category   string     `json:"category"`
+		Rank       int        `json:"rank"` // 0 if it did not make the ranking
+		Strategy   string     `json:"strategy"`
+		Score      float64    `json:"score"`
+		Position   int        `json:"position"`   // place among the run's candidates
+		Candidates int        `json:"candidates"` // size of the run's candidate pool
+		Timeliness timeliness `json:"timeliness"`
+		Relevance  relevance  `json:"relevance"`
+		Source     source     `json:"source"`
+		Buzz       buzz       `json:"buzz"`
+	}{
+		ID:         c.ID,
+		Title:      c.Title,
+		Date:       c.PublishDate,
+		RunID:      c.RunID,
+		Category:   c.Category,
+		Rank:       c.Rank,
+		Strategy:   opts.Strategy,
+		Score:      c.Score,
+		Position:   c.Position,
+		Candidates: total,
+		Timeliness: timeliness{
+			HoursAgo:      c.HoursAgo,
+			HalfLifeHours: params.HalfLifeHours,
+			scorePart:     scorePart{c.Timeliness, params.Timeliness, parts.Timeliness},
+		},
+		Relevance: relevance{
+			Keywords:  c.Keywords,
+			Hits:      c.Hits,
+			MaxHits:   params.KeywordCap,
+			scorePart: scorePart{c.Relevance, params.Relevance, parts.Relevance},
+		},
+		Source: source{
+			Name:      c.SourceName,
+			Default:   c.WeightDefault,
+			scorePart: scorePart{c.SourceWeight, params.Source, parts.Source},
+		},
+		Buzz: buzz{
+			Sources:   c.Sources,
+			Cap:       params.BuzzCap,
+			scorePart: scorePart{c.Buzz, params.Buzz, parts.Buzz},
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// tagsOf labels the tag names of an article, in the order of catalog. Tags
+// no longer in the catalog keep their name as the label and come last.
+func tagsOf(names []string, catalog []fetcher.Tag) []fetcher.Tag {
+	tags := []fetcher.Tag{}
+	for _, t := range catalog {
+		if slices.Contains(names, t.Name) {
+			tags = append(tags, t)
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(catalog, func(t fetcher.Tag) bool { return t.Name == name }) {
+			tags = append(tags, fetcher.Tag{Name: name, Label: name})
+		}
+	}
+	return tags
+}
+
+// entitiesOf types the entity names of an article, in gazetteer order.
+// Entities no longer in the gazetteer have no type and come last.
+func entitiesOf(names []string, gazetteer fetcher.Gazetteer) []entityRef {
+	refs := []entityRef{}
+	for _, e := range gazetteer {
+		if slices.Contains(names, e.Name) {
+			refs = append(refs, entityRef{Name: e.Name, Type: e.Type})
+		}
+	}
+	for _, name := range names {
+		if !slices.ContainsFunc(gazetteer, func(e fetcher.Entity) bool { return e.Name == name }) {
+			refs = append(refs, entityRef{Name: name})
+		}
+	}
+	return refs
+}
+
+// pageParams reads ?limit= (1-100, default 20) and ?offset= (default 0). msg
+// describes an invalid value.
+func pageParams(r *http.Request) (limit, offset int, msg string) {
+	limit = 20
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > 100 {
+			return 0, 0, "limit 参数无效 (1-100)"
+		}
+		limit = n
+	}
+	if v := r.URL.Query().Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return 0, 0, "offset 参数无效"
+		}
+		offset = n
+	}
+	return limit, offset, ""
+}
+
+// forceParam reports whether the request asks to rewrite locked editions.
+func forceParam(r *http.Request) bool {
+	force, _ := strconv.ParseBool(r.URL.Query().Get("force"))
+	return force
+}
+
 func (h *NewsHandler) Navigate(w http.ResponseWriter, r *http.Request) {
 	date := r.URL.Query().Get("date")
 	direction := r.URL.Query().Get("dir") // "prev" or "next"
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+	"top-ai-news/internal/database"
+	"top-ai-news/internal/fetcher"
+	"top-ai-news/internal/model"
+)
+
+func TestExplainStrategy(t *testing.T) {
+	db, err := database.New(filepath.Join(t.TempDir(), "news.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	f, err := fetcher.New(db, "", time.UTC)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := NewNewsHandler(db, f)
+
+	// The default weighted scorer ranks "covered" first; decay prefers the
+	// fresher "fresh".
+	runID, err := db.CreateFetchRun("2026-02-01", "cli", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cands := []model.Candidate{
+		{News: model.News{Title: "covered", CanonicalURL: "https://a.example/covered", Category: "global", PublishDate: "2026-02-01", Rank: 1},
+			Position: 1, HoursAgo: 12, Hits: 5, SourceWeight: 1, Sources: 4},
+		{News: model.News{Title: "fresh", CanonicalURL: "https://a.example/fresh", Category: "global", PublishDate: "2026-02-01"},
+			Position: 2, HoursAgo: 0, Hits: 5, SourceWeight: 0.2, Sources: 1},
+	}
+	if _, err := db.SaveRanking(runID, "2026-02-01", cands, false); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := db.ListCandidates(runID, "2026-02-01", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fresh int64
+	for _, c := range stored {
+		if c.Title == "fresh" {
+			fresh = c.ID
+		}
+	}
+
+	tests := []struct {
+		query    string
+		strategy string
+		position int
+		rank     int
+	}{
+		{"", "weighted", 2, 0},
+		{"?strategy=weighted", "weighted", 2, 0},
+		{"?strategy=decay", "decay", 1, 1},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.Explain(w, httptest.NewRequest("GET", fmt.Sprintf("/api/news/%d/explain%s", fresh, tt.query), nil))
+		var got struct {
+			Strategy string `json:"strategy"`
+			Position int    `json:"position"`
+			Rank     int    `json:"rank"`
+		}
+		if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+			t.Fatalf("%q: %d %v", tt.query, w.Code, err)
+		}
+		if got.Strategy != tt.strategy || got.Position != tt.position || got.Rank != tt.rank {
+			t.Errorf("%q: %s at %d rank %d, want %s at %d rank %d",
+				tt.query, got.Strategy, got.Position, got.Rank, tt.strategy, tt.position, tt.rank)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+	"top-ai-news/internal/database"
+)
+
+// snippetWidth is the length, in characters, of a summary snippet.
+const snippetWidth = 80
+
+// Search finds ranked news by title and summary. ?q= holds the words to
+// match, all of them, with "quoted phrases" kept together; ?from= and ?to=
+// bound the ranking date, ?category= and ?source= filter exactly. Results
+// come with the title and a summary snippet highlighted with <mark>, ordered
+// by relevance discounted by age; ?limit= defaults to 20 and ?offset= to 0.
+// Route: /api/search
+func (h *NewsHandler) Search(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.TrimSpace(query.Get("q"))
+	terms := database.SearchTerms(q)
+	if len(terms) == 0 {
+		http.Error(w, "缺少搜索词", http.StatusBadRequest)
+		return
+	}
+	opts := database.SearchOptions{
+		Terms:    terms,
+		From:     query.Get("from"),
+		To:       query.Get("to"),
+		Category: query.Get("category"),
+		Source:   query.Get("source"),
+	}
+	for _, d := range []string{opts.From, opts.To} {
+		if _, err := time.Parse("2006-01-02", d); d != "" && err != nil {
+			http.Error(w, "日期格式无效，请使用 yyyy-MM-dd", http.StatusBadRequest)
+			return
+		}
+	}
+	if opts.From != "" && opts.To != "" && opts.From > opts.To {
+		http.Error(w, "from 不能晚于 to", http.StatusBadRequest)
+		return
+	}
+	var msg string
+	if opts.Limit, opts.Offset, msg = pageParams(r); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	hits, total, err := h.db.SearchNews(opts)
+	if err != nil {
+		http.Error(w, "搜索失败", http.StatusInternalServerError)
+		return
+	}
+
+	type result struct {
+		newsItem
+		TitleHTML string  `json:"title_html"` // escaped title with the matches in <mark>
+		Snippet   string  `json:"snippet"`    // escaped part of the summary, matches in <mark>
+		Relevance float64 `json:"relevance"`
+	}
+	resp := struct {
+		Query   string   `json:"query"`
+		Terms   []string `json:"terms"`
+		Total   int      `json:"total"`
+		Limit   int      `json:"limit"`
+		Offset  int      `json:"offset"`
+		Results []result `json:"results"`
+	}{
+		Query:   q,
+		Terms:   terms,
+		Total:   total,
+		Limit:   opts.Limit,
+		Offset:  opts.Offset,
+		Results: []result{},
+	}
+	set := h.fetcher.Sources()
+	catalog := set.Tagger().Tags()
+	for _, hit := range hits {
+		resp.Results = append(resp.Results, result{
+			newsItem:  h.newsItem(hit.News, catalog, set.Entities),
+			TitleHTML: highlight(hit.Title, terms),
+			Snippet:   snippet(hit.Summary, terms, snippetWidth),
+			Relevance: hit.Relevance,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// highlight escapes text and wraps the occurrences of terms in <mark>.
+func highlight(text string, terms []string) string {
+	runes := []rune(text)
+	return markup(runes, database.MatchedRunes(runes, terms))
+}
+
+// markup escapes text and wraps its marked characters in <mark>.
+func markup(text []rune, marked []bool) string {
+	var b strings.Builder
+	for i := 0; i < len(text); {
+		j := i + 1
+		for j < len(text) && marked[j] == marked[i] {
+			j++
+		}
+		if marked[i] {
+			b.WriteString("<mark>" + html.EscapeString(string(text[i:j])) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(string(text[i:j])))
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// snippet highlights about width characters of text around the first
+// occurrence of terms, or its start if there is none.
+func snippet(text string, terms []string, width int) string {
+	runes := []rune(text)
+	marked := database.MatchedRunes(runes, terms)
+	start := 0
+	for i, m := range marked {
+		if m {
+			start = max(i-width/4, 0)
+			break
+		}
+	}
+	end := min(start+width, len(runes))
+	start = max(end-width, 0)
+
+	s := markup(runes[start:end], marked[start:end])
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(runes) {
+		s += "…"
+	}
+	return s
+}
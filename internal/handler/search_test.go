@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	tests := []struct {
+		text  string
+		terms []string
+		want  string
+	}{
+		{"OpenAI said AI <b>", []string{"ai"}, "OpenAI said <mark>AI</mark> &lt;b&gt;"},
+		{"DeepSeek开源大模型", []string{"deepseek", "开源"}, "<mark>DeepSeek开源</mark>大模型"},
+		{"Tom & Jerry", []string{"jerry"}, "Tom &amp; <mark>Jerry</mark>"},
+		{"no match", []string{"x"}, "no match"},
+	}
+	for _, tt := range tests {
+		if got := highlight(tt.text, tt.terms); got != tt.want {
+			t.Errorf("highlight(%q, %q) = %q, want %q", tt.text, tt.terms, got, tt.want)
+		}
+	}
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("前", 50) + "融资" + strings.Repeat("后", 50)
+	tests := []struct {
+		text  string
+		terms []string
+		width int
+		want  string
+	}{
+		{"短摘要提到融资", []string{"融资"}, 20, "短摘要提到<mark>融资</mark>"},
+		{long, []string{"融资"}, 10, "…" + strings.Repeat("前", 2) + "<mark>融资</mark>" + strings.Repeat("后", 6) + "…"},
+		{long, []string{"没有"}, 4, strings.Repeat("前", 4) + "…"},
+		{strings.Repeat("x", 10) + "融资", []string{"融资"}, 4, "…xx<mark>融资</mark>"},
+		// A word cut at the edge of the snippet is still not a match.
+		{"xxxxsaid ai", []string{"ai"}, 5, "…id <mark>ai</mark>"},
+	}
+	for _, tt := range tests {
+		if got := snippet(tt.text, tt.terms, tt.width); got != tt.want {
+			t.Errorf("snippet(%q, %q, %d) = %q, want %q", tt.text, tt.terms, tt.width, got, tt.want)
+		}
+	}
+}
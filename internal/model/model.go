@@ -2,16 +2,76 @@ package model
 
 import "time"
 
+// Ranking status values. A dropped article fell out of its day's ranking; the
+// row is kept so its comments stay reachable. Candidates that did not make the
+// ranking are reported as NewsCandidate.
+const (
+	NewsActive    = "active"
+	NewsDropped   = "dropped"
+	NewsCandidate = "candidate"
+)
+
+// News is an article as placed in a day's ranking. ID is the article ID, which
+// stays the same across refreshes and days.
 type News struct {
-	ID          int64     `json:"id"`
-	Title       string    `json:"title"`
-	Summary     string    `json:"summary"`
-	SourceURL   string    `json:"source_url"`
-	SourceName  string    `json:"source_name"`
-	Category    string    `json:"category"` // "domestic" or "global"
-	PublishDate string    `json:"publish_date"`
-	Rank        int       `json:"rank"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID           int64     `json:"id"`
+	Title        string    `json:"title"`
+	Summary      string    `json:"summary"`
+	SourceURL    string    `json:"source_url"`
+	CanonicalURL string    `json:"-"` // identifies the article across refreshes
+	SourceName   string    `json:"source_name"`
+	PublishedAt  time.Time `json:"published_at"` // when the feed published the article
+	Category     string    `json:"category"`     // name of a configured category
+	PublishDate  string    `json:"publish_date"` // ranking date
+	Rank         int       `json:"rank"`
+	Score        float64   `json:"score"`
+	Status       string    `json:"status"`
+	CreatedAt    time.Time `json:"created_at"`
+
+	AlsoCoveredBy []Coverage `json:"also_covered_by,omitempty"` // the same story from other sources
+	Tags          []string   `json:"tags,omitempty"`            // topic tags
+	Entities      []string   `json:"entities,omitempty"`        // canonical names of the entities mentioned
+}
+
+// SearchHit is a News item matching a search query.
+type SearchHit struct {
+	News
+	Relevance float64 `json:"relevance"` // bm25 relevance discounted by age, higher is better
+}
+
+// Coverage is another source's article about the same story as a News item.
+type Coverage struct {
+	ID         int64  `json:"id"`
+	Title      string `json:"title"`
+	SourceURL  string `json:"source_url"`
+	SourceName string `json:"source_name"`
+}
+
+// Candidate is an article considered for a day's ranking in a fetch run,
+// with the components its score was computed from.
+type Candidate struct {
+	News                 // Rank is 0 unless the article made the ranking
+	RunID        int64   `json:"run_id"`
+	Position     int     `json:"position"` // 1-based place among the run's candidates in its category
+	Timeliness   float64 `json:"timeliness"`
+	Relevance    float64 `json:"relevance"`
+	SourceWeight float64 `json:"source_weight"`
+	Buzz         float64 `json:"buzz"`
+
+	HoursAgo      float64  `json:"hours_ago"`      // article age when scored
+	Keywords      []string `json:"keywords"`       // high-value keywords that matched
+	Hits          float64  `json:"hits"`           // sum of the weights of Keywords
+	WeightDefault bool     `json:"weight_default"` // source weight fell back to the default
+	Sources       int      `json:"sources"`        // sources that covered the story
+
+	Members []News `json:"-"` // other sources' articles clustered with this one
+}
+
+// RankingChange summarises how SaveRanking changed a day's ranking.
+type RankingChange struct {
+	Inserted int
+	Updated  int
+	Dropped  int
 }
 
 type Comment struct {
@@ -28,9 +88,86 @@ type CommentInput struct {
 }
 
 type NewsListResponse struct {
-	Date     string `json:"date"`
-	Domestic []News `json:"domestic"`
-	Global   []News `json:"global"`
-	HasPrev  bool   `json:"has_prev"`
-	HasNext  bool   `json:"has_next"`
+	Date     string    `json:"date"`
+	Sections []Section `json:"sections"`
+	HasPrev  bool      `json:"has_prev"`
+	HasNext  bool      `json:"has_next"`
+}
+
+// Section is the ranking of one category on a date.
+type Section struct {
+	Category string `json:"category"`
+	Label    string `json:"label"`
+	Icon     string `json:"icon,omitempty"`
+	News     []News `json:"news"`
+}
+
+// FeedCache holds the HTTP validators and last body of a feed, used for
+// conditional GET requests.
+type FeedCache struct {
+	FeedURL      string
+	ETag         string
+	LastModified string
+	Body         []byte
+	UpdatedAt    time.Time
+}
+
+// Edition is the publication state of a day's ranking.
+type Edition struct {
+	Date      string    `json:"date"`
+	State     string    `json:"state"` // draft, published or locked
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// FeedBreaker is the persisted circuit-breaker state of a feed.
+type FeedBreaker struct {
+	FeedURL    string     `json:"feed_url"`
+	SourceName string     `json:"source_name"`
+	Failures   int        `json:"consecutive_failures"` // consecutive failed runs
+	LastError  string     `json:"last_error,omitempty"`
+	OpenedAt   *time.Time `json:"opened_at,omitempty"` // set while the breaker is open
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// FetchRun records one FetchAndStore execution.
+type FetchRun struct {
+	ID         int64            `json:"id"`
+	Date       string           `json:"date"`
+	Trigger    string           `json:"trigger"` // "scheduler", "api" or "cli"
+	Status     string           `json:"status"`  // "running", "succeeded" or "failed"
+	Stored     int              `json:"stored"`
+	Error      string           `json:"error,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Sources    []FetchRunSource `json:"sources,omitempty"`
+}
+
+// FetchRunSource records how a single feed fared within a run.
+type FetchRunSource struct {
+	RunID      int64  `json:"run_id"`
+	SourceName string `json:"source_name"`
+	FeedURL    string `json:"feed_url"`
+	Status     string `json:"status"` // "ok", "not_modified", "error" or "skipped"
+	HTTPStatus int    `json:"http_status,omitempty"`
+	Items      int    `json:"items"` // items in the feed
+	Kept       int    `json:"kept"`  // items left after keyword filtering
+	Error      string `json:"error,omitempty"`
+	DurationMS int64  `json:"duration_ms"`
+}
+
+// SourceHealth aggregates a feed's run records over a time window.
+type SourceHealth struct {
+	SourceName    string     `json:"source_name"`
+	FeedURL       string     `json:"feed_url"`
+	Runs          int        `json:"runs"`
+	OK            int        `json:"ok"` // includes not_modified
+	Errors        int        `json:"errors"`
+	Skipped       int        `json:"skipped"`
+	Items         int        `json:"items"`
+	Kept          int        `json:"kept"`
+	LastStatus    string     `json:"last_status"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastRunAt     time.Time  `json:"last_run_at"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+	LastKeptAt    *time.Time `json:"last_kept_at,omitempty"` // last run that yielded at least one AI item
 }
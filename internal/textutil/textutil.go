@@ -0,0 +1,11 @@
+// Package textutil holds the text helpers shared by the fetcher and the
+// search index.
+package textutil
+
+import "unicode"
+
+// IsCJK reports whether r belongs to a script written without spaces between
+// words.
+func IsCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
@@ -0,0 +1,59 @@
+// Package urlnorm reduces article URLs to a canonical form so the same story
+// can be recognised across refreshes.
+package urlnorm
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// trackingParams are query parameters that never change the page.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "mc_cid": true, "mc_eid": true,
+	"ref": true, "ref_src": true, "spm": true,
+}
+
+// Canonical returns raw with the scheme forced to https, the host lowercased
+// and stripped of "www." and default ports, tracking parameters (utm_* and the
+// like) and the fragment removed, the remaining query sorted and any trailing
+// slash trimmed. A URL that does not parse is returned trimmed.
+func Canonical(raw string) string {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		return raw
+	}
+
+	host := strings.ToLower(u.Hostname())
+	host = strings.TrimPrefix(host, "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	q := u.Query()
+	for k := range q {
+		lk := strings.ToLower(k)
+		if strings.HasPrefix(lk, "utm_") || trackingParams[lk] {
+			q.Del(k)
+		}
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var query []string
+	for _, k := range keys {
+		for _, v := range q[k] {
+			query = append(query, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	out := "https://" + host + path
+	if len(query) > 0 {
+		out += "?" + strings.Join(query, "&")
+	}
+	return out
+}
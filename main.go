@@ -2,12 +2,20 @@ package main
 
 import (
 	"embed"
+	"errors"
 	"flag"
 	"io/fs"
 	"log"
+	"net"
 	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
+	_ "time/tzdata" // edition timezone must resolve even without system zoneinfo
+	"top-ai-news/internal/auth"
 	"top-ai-news/internal/database"
 	"top-ai-news/internal/fetcher"
 	"top-ai-news/internal/handler"
@@ -17,10 +25,45 @@ import (
 var webFS embed.FS
 
 func main() {
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	port := flag.String("port", "8080", "服务端口")
 	dbPath := flag.String("db", "data.db", "数据库文件路径")
+	sourcesPath := flag.String("sources", "", "RSS 源配置文件路径 (YAML/JSON)，为空则使用内置源")
+	tz := timezoneFlag(flag.CommandLine)
+	var schedules scheduleFlags
+	flag.Var(&schedules, "schedule", "定时任务 [名称:]任务=cron 表达式，任务为 refresh 或 final，可重复；也可通过环境变量 NEWS_SCHEDULE 配置（以 ; 分隔），默认 "+fetcher.DefaultSchedule)
+	jitter := flag.Duration("schedule-jitter", time.Minute, "每次定时任务的随机延迟上限")
+	catchUp := flag.Duration("schedule-catchup", 24*time.Hour, "启动时补跑该时间窗口内错过的定时任务，0 为不补跑")
+	authPath := flag.String("auth", "", "管理员令牌配置文件路径 (YAML)，也可通过环境变量 "+auth.EnvTokens+" 配置")
+	trusted := flag.String("trusted-proxies", os.Getenv("NEWS_TRUSTED_PROXIES"), "可信反向代理的 IP 或 CIDR，以逗号分隔；仅来自这些地址的 X-Real-IP / X-Forwarded-For 会被采信，也可通过环境变量 NEWS_TRUSTED_PROXIES 配置")
 	flag.Parse()
 
+	proxies, err := parseProxies(*trusted)
+	if err != nil {
+		log.Fatalf("可信代理配置无效: %v", err)
+	}
+
+	loc, err := time.LoadLocation(*tz)
+	if err != nil {
+		log.Fatalf("时区无效: %v", err)
+	}
+	sched, err := fetcher.ParseSchedules(schedules.specs())
+	if err != nil {
+		log.Fatalf("定时任务配置无效:\n%v", err)
+	}
+
+	// Load admin tokens
+	authn, err := auth.Load(*authPath)
+	if err != nil {
+		log.Fatalf("加载管理员令牌失败:\n%v", err)
+	}
+	if !authn.Enabled() {
+		log.Println("⚠ 未配置管理员令牌，管理接口将拒绝所有请求")
+	}
+
 	// Initialize database
 	db, err := database.New(*dbPath)
 	if err != nil {
@@ -29,13 +72,32 @@ func main() {
 	defer db.Close()
 
 	// Initialize fetcher with RSS scheduler
-	f := fetcher.New(db)
-	f.StartScheduler(4 * time.Hour)
+	f, err := fetcher.New(db, *sourcesPath, loc)
+	if err != nil {
+		log.Fatalf("加载 RSS 源配置失败:\n%v", err)
+	}
+	log.Printf("✓ 日报时区 %s，今日为 %s", loc, f.Today())
+	if *sourcesPath != "" {
+		log.Printf("✓ 从 %s 加载 %d 个 RSS 源", *sourcesPath, len(f.Sources().Feeds))
+	}
+	f.StartScheduler(fetcher.SchedulerOptions{Schedules: sched, Jitter: *jitter, CatchUp: *catchUp})
+	f.WatchSources(5 * time.Second)
 	defer f.Stop()
 
+	// Reload sources on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			log.Println("📝 收到 SIGHUP，重新加载 RSS 源配置")
+			f.ReloadSources()
+		}
+	}()
+
 	// Initialize handlers
 	newsHandler := handler.NewNewsHandler(db, f)
 	commentHandler := handler.NewCommentHandler(db)
+	adminHandler := handler.NewAdminHandler(db, f)
 
 	// Setup routes
 	mux := http.NewServeMux()
@@ -49,10 +111,44 @@ func main() {
 
 	// API routes
 	mux.HandleFunc("/api/news", corsMiddleware(newsHandler.GetNews))
-	mux.HandleFunc("/api/news/fetch", corsMiddleware(methodOnly("POST", newsHandler.FetchNews)))
+	mux.HandleFunc("/api/news/fetch", corsMiddleware(methodOnly("POST", adminOnly(authn, proxies, auth.ScopeFetch, newsHandler.FetchNews))))
+	mux.HandleFunc("/api/news/fetch/", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeFetch, newsHandler.GetFetchJob))))
 	mux.HandleFunc("/api/news/dates", corsMiddleware(newsHandler.GetDates))
 	mux.HandleFunc("/api/news/navigate", corsMiddleware(newsHandler.Navigate))
+	mux.HandleFunc("/api/search", corsMiddleware(methodOnly("GET", newsHandler.Search)))
+	mux.HandleFunc("/api/admin/sources", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeSources, adminHandler.GetSources))))
+	mux.HandleFunc("/api/admin/backfill", corsMiddleware(methodOnly("POST", adminOnly(authn, proxies, auth.ScopeFetch, adminHandler.Backfill))))
+	mux.HandleFunc("/api/admin/editions", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeFetch, adminHandler.GetEditions))))
+	mux.HandleFunc("/api/admin/editions/", corsMiddleware(methodOnly("POST", adminOnly(authn, proxies, auth.ScopeFetch, adminHandler.SetEditionState))))
+	mux.HandleFunc("/api/admin/candidates", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeFetch, adminHandler.GetCandidates))))
+	mux.HandleFunc("/api/admin/runs", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeFetch, adminHandler.GetRuns))))
+	mux.HandleFunc("/api/admin/sources/health", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeSources, adminHandler.GetSourceHealth))))
+	mux.HandleFunc("/api/admin/breakers", corsMiddleware(methodOnly("GET", adminOnly(authn, proxies, auth.ScopeSources, adminHandler.GetBreakers))))
+	mux.HandleFunc("/api/admin/sources.opml", corsMiddleware(adminOnly(authn, proxies, auth.ScopeSources, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+			adminHandler.ExportOPML(w, r)
+		case http.MethodPost:
+			adminHandler.ImportOPML(w, r)
+		default:
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		}
+	})))
+	mux.HandleFunc("/api/comments/", corsMiddleware(methodOnly("DELETE", adminOnly(authn, proxies, auth.ScopeModeration, commentHandler.DeleteComment))))
+	mux.HandleFunc("/api/entities/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// Route: /api/entities/{name}/news
+		if !strings.HasSuffix(r.URL.Path, "/news") {
+			http.NotFound(w, r)
+			return
+		}
+		methodOnly("GET", newsHandler.EntityNews)(w, r)
+	}))
 	mux.HandleFunc("/api/news/", corsMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		// Route: /api/news/{id}/explain
+		if strings.HasSuffix(r.URL.Path, "/explain") {
+			methodOnly("GET", newsHandler.Explain)(w, r)
+			return
+		}
 		// Route: /api/news/{id}/comments
 		if !strings.HasSuffix(r.URL.Path, "/comments") {
 			http.NotFound(w, r)
@@ -82,11 +178,49 @@ func main() {
 	}
 }
 
+// timezoneFlag registers the -tz flag. The edition timezone decides when a new
+// day's list starts; it defaults to $NEWS_TZ, then Asia/Shanghai.
+func timezoneFlag(fset *flag.FlagSet) *string {
+	def := os.Getenv("NEWS_TZ")
+	if def == "" {
+		def = "Asia/Shanghai"
+	}
+	return fset.String("tz", def, "日报时区 (IANA 名称，如 Asia/Shanghai)，也可通过环境变量 NEWS_TZ 配置")
+}
+
+// scheduleFlags collects repeated -schedule flags.
+type scheduleFlags []string
+
+func (s *scheduleFlags) String() string { return strings.Join(*s, "; ") }
+
+func (s *scheduleFlags) Set(v string) error {
+	*s = append(*s, v)
+	return nil
+}
+
+// specs returns the configured schedules, falling back to $NEWS_SCHEDULE and
+// then to the default four-hour refresh.
+func (s scheduleFlags) specs() []string {
+	if len(s) > 0 {
+		return s
+	}
+	var specs []string
+	for _, spec := range strings.Split(os.Getenv("NEWS_SCHEDULE"), ";") {
+		if spec = strings.TrimSpace(spec); spec != "" {
+			specs = append(specs, spec)
+		}
+	}
+	if len(specs) == 0 {
+		specs = []string{fetcher.DefaultSchedule}
+	}
+	return specs
+}
+
 func corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 		if r.Method == http.MethodOptions {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -104,3 +238,88 @@ func methodOnly(method string, next http.HandlerFunc) http.HandlerFunc {
 		next(w, r)
 	}
 }
+
+// adminOnly requires a bearer token with the given scope. Rejected attempts are
+// logged with the client IP.
+func adminOnly(a *auth.Authenticator, proxies trustedProxies, scope auth.Scope, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, err := a.Authorize(r, scope)
+		if err != nil {
+			log.Printf("🚫 拒绝管理请求 %s %s from %s (token=%q scope=%s): %v",
+				r.Method, r.URL.Path, proxies.clientIP(r), name, scope, err)
+			if errors.Is(err, auth.ErrForbidden) {
+				http.Error(w, "权限不足", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "需要管理员令牌", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// trustedProxies are the reverse proxies whose forwarding headers are believed.
+type trustedProxies []netip.Prefix
+
+// parseProxies parses a comma-separated list of IPs and CIDRs.
+func parseProxies(list string) (trustedProxies, error) {
+	var out trustedProxies
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		if p, err := netip.ParsePrefix(s); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return out, nil
+}
+
+// contains reports whether ip is one of the trusted proxies.
+func (t trustedProxies) contains(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range t {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP returns the caller's address. The X-Real-IP and X-Forwarded-For
+// headers can be set by anyone, so they are only used when the request comes
+// from a trusted proxy; X-Forwarded-For is then read from the right, skipping
+// the trusted hops.
+func (t trustedProxies) clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	if !t.contains(host) {
+		return host
+	}
+	if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+		hops := strings.Split(fwd, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip := strings.TrimSpace(hops[i])
+			if ip != "" && (i == 0 || !t.contains(ip)) {
+				return ip
+			}
+		}
+	}
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	return host
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := parseProxies("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		remote, xff, xRealIP string
+		proxies              trustedProxies
+		want                 string
+	}{
+		// A client talking to the service directly cannot choose its IP.
+		{"203.0.113.5:4711", "198.51.100.1", "198.51.100.2", proxies, "203.0.113.5"},
+		{"127.0.0.1:4711", "198.51.100.1", "", nil, "127.0.0.1"},
+		// Behind proxies, the rightmost untrusted hop is the client; what the
+		// client put in front of it is ignored.
+		{"127.0.0.1:4711", "6.6.6.6, 198.51.100.7, 10.0.0.2", "", proxies, "198.51.100.7"},
+		{"10.1.2.3:4711", "198.51.100.7", "6.6.6.6", proxies, "198.51.100.7"},
+		{"[::ffff:10.1.2.3]:4711", "198.51.100.7", "", proxies, "198.51.100.7"},
+		{"127.0.0.1:4711", "10.0.0.3, 10.0.0.2", "", proxies, "10.0.0.3"},
+		{"127.0.0.1:4711", "", "198.51.100.9", proxies, "198.51.100.9"},
+		{"127.0.0.1:4711", "", "", proxies, "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("POST", "/api/news/fetch", nil)
+		r.RemoteAddr = tt.remote
+		if tt.xff != "" {
+			r.Header.Set("X-Forwarded-For", tt.xff)
+		}
+		if tt.xRealIP != "" {
+			r.Header.Set("X-Real-IP", tt.xRealIP)
+		}
+		if got := tt.proxies.clientIP(r); got != tt.want {
+			t.Errorf("from %s (X-Forwarded-For %q, X-Real-IP %q): %s, want %s", tt.remote, tt.xff, tt.xRealIP, got, tt.want)
+		}
+	}
+}
+
+func TestParseProxies(t *testing.T) {
+	for _, bad := range []string{"nope", "10.0.0.0/33", "127.0.0.1:80"} {
+		if _, err := parseProxies(bad); err == nil {
+			t.Errorf("parseProxies(%q) accepted", bad)
+		}
+	}
+	proxies, err := parseProxies(" 192.168.1.0/24 ,, ::1 ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for ip, want := range map[string]bool{"192.168.1.77": true, "192.168.2.1": false, "::1": true, "not-an-ip": false} {
+		if got := proxies.contains(ip); got != want {
+			t.Errorf("contains(%q) = %v, want %v", ip, got, want)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 const API = '.';
 let currentDate = '';
+let editionToday = ''; // today's date in the server's edition timezone
 let currentNewsId = null;
+let currentTag = ''; // topic tag filter, '' = all
+let currentQuery = ''; // search being shown, kept for "加载更多"
+let currentEntity = ''; // entity being shown, kept for "加载更多"
 
 // Initialize
 document.addEventListener('DOMContentLoaded', () => {
@@ -8,52 +12,82 @@ document.addEventListener('DOMContentLoaded', () => {
 });
 
 async function loadNews(date) {
-    const params = date ? `?date=${date}` : '';
+    const query = new URLSearchParams();
+    if (date) query.set('date', date);
+    if (currentTag) query.set('tag', currentTag);
+    const params = query.toString() ? `?${query}` : '';
     try {
         const resp = await fetch(`${API}/api/news${params}`);
         if (!resp.ok) throw new Error('加载失败');
         const data = await resp.json();
 
         currentDate = data.date;
+        editionToday = data.today;
         document.getElementById('currentDate').textContent = formatDate(data.date);
+        renderEditionState(data.state);
 
-        renderNewsList('domesticNews', data.domestic);
-        renderNewsList('globalNews', data.global);
+        renderTagBar(data.tags);
+        renderSections(data.sections);
 
         document.getElementById('prevBtn').disabled = !data.has_prev;
         document.getElementById('nextBtn').disabled = !data.has_next;
 
-        // Show "today" button if not viewing today
-        const today = new Date().toISOString().split('T')[0];
+        // Show "today" button if not viewing today (in the edition timezone)
         document.getElementById('todayBtn').style.display =
-            data.date === today ? 'none' : 'inline-block';
+            data.date === data.today ? 'none' : 'inline-block';
     } catch (err) {
         console.error('加载新闻失败:', err);
-        document.getElementById('domesticNews').innerHTML =
-            '<div class="empty-state">加载失败，请刷新重试</div>';
-        document.getElementById('globalNews').innerHTML =
+        document.getElementById('newsGrid').innerHTML =
             '<div class="empty-state">加载失败，请刷新重试</div>';
     }
 }
 
-function renderNewsList(containerId, news) {
-    const container = document.getElementById(containerId);
-    if (!news || news.length === 0) {
-        container.innerHTML = '<div class="empty-state">暂无新闻数据</div>';
+// Edition badge: drafts are still being refreshed and show nothing
+const EDITION_LABELS = { published: '已发布', locked: '🔒 已定稿' };
+
+function renderEditionState(state) {
+    const el = document.getElementById('editionState');
+    const label = EDITION_LABELS[state];
+    el.textContent = label || '';
+    el.className = `edition-state ${state || ''}`;
+    el.style.display = label ? 'inline-block' : 'none';
+}
+
+// One section per category, in the order the server configured them
+function renderSections(sections) {
+    const grid = document.getElementById('newsGrid');
+    if (!sections || sections.length === 0) {
+        grid.innerHTML = '<div class="empty-state">暂无新闻数据</div>';
         return;
     }
 
-    container.innerHTML = news.map(item => `
+    grid.innerHTML = sections.map(s => `
+        <section class="news-section ${escapeHtml(s.category)}">
+            <h2>${s.icon ? `<span class="flag">${escapeHtml(s.icon)}</span> ` : ''}${escapeHtml(s.label)}</h2>
+            <div class="news-list">${renderNewsList(s.news)}</div>
+        </section>
+    `).join('');
+}
+
+function renderNewsList(news) {
+    if (!news || news.length === 0) {
+        return '<div class="empty-state">暂无新闻数据</div>';
+    }
+
+    return news.map(item => `
         <div class="news-item">
             <div class="news-title-row">
                 <span class="news-rank rank-${item.rank}">${item.rank}</span>
                 <span class="news-title">
-                    <a href="${escapeHtml(item.source_url)}" target="_blank" rel="noopener">${escapeHtml(item.title)}</a>
+                    <a href="${escapeHtml(item.source_url)}" target="_blank" rel="noopener">${item.title_html || escapeHtml(item.title)}</a>
                 </span>
             </div>
-            ${item.summary ? `<div class="news-summary">${escapeHtml(item.summary)}</div>` : ''}
+            ${item.summary ? `<div class="news-summary">${item.snippet || escapeHtml(item.summary)}</div>` : ''}
             <div class="news-meta">
                 <span class="source-tag">${escapeHtml(item.source_name)}</span>
+                ${renderTags(item.tags)}
+                ${renderEntities(item.entities)}
+                ${renderCoverage(item.also_covered_by)}
                 <button class="comment-trigger" onclick="openComments(${item.id}, '${escapeHtml(item.title).replace(/'/g, "\\'")}')">
                     💬 评论${item.comment_count > 0 ? ` (${item.comment_count})` : ''}
                 </button>
@@ -62,6 +96,109 @@ function renderNewsList(containerId, news) {
     `).join('');
 }
 
+// Topic filter: "全部" plus every tag the server can assign
+function renderTagBar(tags) {
+    const bar = document.getElementById('tagBar');
+    if (!tags || tags.length === 0) {
+        bar.innerHTML = '';
+        return;
+    }
+    const chip = (name, label) =>
+        `<button class="tag-chip${name === currentTag ? ' active' : ''}" onclick="filterByTag('${escapeHtml(name)}')">${escapeHtml(label)}</button>`;
+    bar.innerHTML = chip('', '全部') + tags.map(t => chip(t.name, t.label)).join('');
+}
+
+function renderTags(tags) {
+    if (!tags || tags.length === 0) return '';
+    return tags.map(t =>
+        `<button class="tag-chip" onclick="filterByTag('${escapeHtml(t.name)}')">#${escapeHtml(t.label)}</button>`
+    ).join('');
+}
+
+function filterByTag(tag) {
+    currentTag = tag;
+    loadNews(currentDate);
+}
+
+// Entity chips open the news that mention the entity
+const ENTITY_ICONS = { org: '🏢', model: '🤖', person: '👤' };
+
+function renderEntities(entities) {
+    if (!entities || entities.length === 0) return '';
+    return entities.map(e =>
+        `<button class="entity-chip" data-entity="${escapeAttr(e.name)}" onclick="showEntity(this.dataset.entity)">${ENTITY_ICONS[e.type] || ''}${escapeHtml(e.name)}</button>`
+    ).join('');
+}
+
+async function showEntity(name, offset = 0) {
+    try {
+        const resp = await fetch(`${API}/api/entities/${encodeURIComponent(name)}/news?offset=${offset}`);
+        if (!resp.ok) throw new Error('加载失败');
+        const data = await resp.json();
+        currentEntity = data.entity.name;
+        const icon = ENTITY_ICONS[data.entity.type] || '';
+        renderListView(`${icon}${escapeHtml(data.entity.name)} 相关新闻 (${data.total})`, data.news, data,
+            `showEntity(currentEntity, ${data.offset + data.news.length})`);
+    } catch (err) {
+        console.error('加载实体新闻失败:', err);
+        alert('加载失败，请重试');
+    }
+}
+
+// Search results come with the matches highlighted by the server
+function searchNews(event) {
+    event.preventDefault();
+    const q = document.getElementById('searchInput').value.trim();
+    if (q) showSearch(q);
+}
+
+async function showSearch(q, offset = 0) {
+    currentQuery = q;
+    try {
+        const resp = await fetch(`${API}/api/search?${new URLSearchParams({ q, offset })}`);
+        if (!resp.ok) throw new Error(await resp.text());
+        const data = await resp.json();
+        renderListView(`🔍 “${escapeHtml(data.query)}” 的搜索结果 (${data.total})`, data.results, data,
+            `showSearch(currentQuery, ${data.offset + data.results.length})`);
+    } catch (err) {
+        console.error('搜索失败:', err);
+        alert('搜索失败: ' + err.message);
+    }
+}
+
+// A paged list in place of the day's sections; later pages are appended
+function renderListView(heading, items, page, moreCall) {
+    const grid = document.getElementById('newsGrid');
+    const html = renderNewsList(items);
+    if (page.offset > 0) {
+        grid.querySelector('.load-more')?.remove();
+        grid.querySelector('.news-list').insertAdjacentHTML('beforeend', html);
+    } else {
+        grid.innerHTML = `
+            <section class="news-section list-section">
+                <h2>
+                    <button class="back-btn" onclick="loadNews(currentDate)">← 返回</button>
+                    ${heading}
+                </h2>
+                <div class="news-list">${html}</div>
+            </section>`;
+        document.getElementById('tagBar').innerHTML = '';
+        window.scrollTo(0, 0);
+    }
+    if (page.offset + items.length < page.total) {
+        grid.querySelector('.list-section').insertAdjacentHTML('beforeend',
+            `<button class="load-more" onclick="${moreCall}">加载更多</button>`);
+    }
+}
+
+function renderCoverage(also) {
+    if (!also || also.length === 0) return '';
+    const links = also.map(c =>
+        `<a href="${escapeHtml(c.source_url)}" target="_blank" rel="noopener" title="${escapeHtml(c.title)}">${escapeHtml(c.source_name)}</a>`
+    ).join('、');
+    return `<span class="also-covered">另有 ${also.length} 家报道：${links}</span>`;
+}
+
 async function navigate(dir) {
     try {
         const resp = await fetch(`${API}/api/news/navigate?date=${currentDate}&dir=${dir}`);
@@ -74,7 +211,7 @@ async function navigate(dir) {
 }
 
 function goToday() {
-    loadNews();
+    loadNews(editionToday || undefined);
 }
 
 // Comments
@@ -171,13 +308,21 @@ async function fetchLatestNews() {
     btn.disabled = true;
 
     try {
-        const today = new Date().toISOString().split('T')[0];
-        const resp = await fetch(`${API}/api/news/fetch?date=${today}`, { method: 'POST' });
+        // Without a date the server fetches today's edition in its timezone
+        const resp = await adminFetch(`${API}/api/news/fetch`, { method: 'POST' });
         if (!resp.ok) {
             const text = await resp.text();
             throw new Error(text);
         }
-        await loadNews(today);
+        const { job_id, date } = await resp.json();
+        const job = await waitForJob(job_id, (j) => {
+            if (j.sources && j.sources.length > 0) {
+                btn.textContent = `抓取中 ${j.sources_done}/${j.sources.length}...`;
+            }
+        });
+        if (job.status === 'failed') throw new Error(job.error || '未知错误');
+
+        await loadNews(date);
         btn.textContent = '抓取完成!';
         setTimeout(() => { btn.textContent = originalText; btn.disabled = false; }, 2000);
     } catch (err) {
@@ -187,6 +332,34 @@ async function fetchLatestNews() {
     }
 }
 
+// Poll a fetch job until it succeeds or fails
+async function waitForJob(jobId, onProgress) {
+    for (;;) {
+        const resp = await adminFetch(`${API}/api/news/fetch/${jobId}`);
+        if (!resp.ok) throw new Error(await resp.text());
+        const job = await resp.json();
+        onProgress(job);
+        if (job.status === 'succeeded' || job.status === 'failed') return job;
+        await new Promise(r => setTimeout(r, 1000));
+    }
+}
+
+// Admin requests carry a bearer token kept in localStorage; ask for it on 401/403
+async function adminFetch(url, options = {}) {
+    for (let attempt = 0; attempt < 2; attempt++) {
+        const token = localStorage.getItem('adminToken') || '';
+        const headers = { ...(options.headers || {}) };
+        if (token) headers['Authorization'] = `Bearer ${token}`;
+        const resp = await fetch(url, { ...options, headers });
+        if (resp.status !== 401 && resp.status !== 403) return resp;
+
+        localStorage.removeItem('adminToken');
+        const input = attempt === 0 ? prompt('请输入管理员令牌') : null;
+        if (!input) return resp;
+        localStorage.setItem('adminToken', input.trim());
+    }
+}
+
 // Utilities
 function escapeHtml(text) {
     if (!text) return '';
@@ -195,6 +368,11 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+// escapeHtml for attribute values, which may be quoted either way
+function escapeAttr(text) {
+    return escapeHtml(text).replace(/"/g, '&quot;').replace(/'/g, '&#39;');
+}
+
 function formatDate(dateStr) {
     const d = new Date(dateStr + 'T00:00:00');
     const days = ['日', '一', '二', '三', '四', '五', '六'];